@@ -0,0 +1,298 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// zipEntries makes a zip archive of the entries in the order given.
+// Entries are deflated with the data descriptors, like the ones
+// made on the fly, unless stored is set.
+func zipEntries(t *testing.T, stored bool, entries ...[2]string) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, e := range entries {
+		var (
+			w   interface{ Write([]byte) (int, error) }
+			err error
+		)
+		if stored {
+			w, err = zw.CreateRaw(&zip.FileHeader{
+				Name:               e[0],
+				Method:             zip.Store,
+				CRC32:              crc32.ChecksumIEEE([]byte(e[1])),
+				CompressedSize64:   uint64(len(e[1])),
+				UncompressedSize64: uint64(len(e[1])),
+			})
+		} else {
+			w, err = zw.Create(e[0])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(e[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// serveArchive serves the archive as the download of the folder
+// at /foo, or of the public folder with the key "key".
+func serveArchive(t *testing.T, data []byte) *httptest.Server {
+	archiveServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(data)
+	}))
+	link := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"href": "%s/foo.zip", "method": "GET"}`, archiveServer.URL)
+	}
+	mux.HandleFunc("/v1/disk/resources/download", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("path"), "/foo"; got != want {
+			t.Errorf("Request path query = %v, want %v", got, want)
+		}
+		link(w, r)
+	})
+	mux.HandleFunc("/v1/disk/public/resources/download", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("public_key"), "key"; got != want {
+			t.Errorf("Request public_key query = %v, want %v", got, want)
+		}
+		if got := r.Header.Get("Authorization"); got == "" {
+			t.Errorf("Authorization header is not sent")
+		}
+		link(w, r)
+	})
+	return archiveServer
+}
+
+func TestResources_DownloadArchive(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := zipEntries(t, false, [2]string{"foo/a.txt", "a"})
+	archiveServer := serveArchive(t, data)
+	defer archiveServer.Close()
+
+	buf := new(bytes.Buffer)
+	_, err := client.Resources.DownloadArchive(context.Background(), "/foo", buf, nil)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadArchive returned error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Downloaded archive differs from the served one")
+	}
+}
+
+func TestResources_DownloadArchive_public(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := zipEntries(t, false, [2]string{"foo/a.txt", "a"})
+	archiveServer := serveArchive(t, data)
+	defer archiveServer.Close()
+
+	buf := new(bytes.Buffer)
+	opt := &yadisk.ArchiveOptions{PublicKey: "key"}
+	_, err := client.Resources.DownloadArchive(context.Background(), "", buf, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadArchive returned error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Downloaded archive differs from the served one")
+	}
+}
+
+func TestResources_ExtractArchive(t *testing.T) {
+	for _, stored := range []bool{false, true} {
+		setup()
+		archiveServer := serveArchive(t, zipEntries(t, stored,
+			[2]string{"foo/", ""},
+			[2]string{"foo/a.txt", "a"},
+			[2]string{"foo/sub/b.txt", "bb"},
+			[2]string{"foo/empty.txt", ""},
+		))
+
+		dir := t.TempDir()
+		files, err := client.Resources.ExtractArchive(context.Background(), "/foo", dir, nil)
+		if err != nil {
+			t.Fatalf("Resources.ExtractArchive (stored: %v) returned error %v", stored, err)
+		}
+
+		if got, want := len(files), 3; got != want {
+			t.Errorf("Extracted %v files, want %v", got, want)
+		}
+		for name, want := range map[string]string{
+			"foo/a.txt":     "a",
+			"foo/sub/b.txt": "bb",
+			"foo/empty.txt": "",
+		} {
+			data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil || string(data) != want {
+				t.Errorf("File %v contents are %q, %v, want %q", name, data, err, want)
+			}
+		}
+
+		archiveServer.Close()
+		teardown()
+	}
+}
+
+func TestResources_ExtractArchive_path_traversal(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "foo/../../evil.txt", "/evil.txt", `..\evil.txt`} {
+		setup()
+		archiveServer := serveArchive(t, zipEntries(t, false, [2]string{name, "evil"}))
+
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "dir")
+		_, err := client.Resources.ExtractArchive(context.Background(), "/foo", dir, nil)
+
+		if !errors.Is(err, yadisk.ErrInvalidArchive) {
+			t.Errorf("Entry %q: error is %v, want %v", name, err, yadisk.ErrInvalidArchive)
+		}
+		if _, err := os.Stat(filepath.Join(parent, "evil.txt")); err == nil {
+			t.Errorf("Entry %q is extracted outside of the folder", name)
+		}
+
+		archiveServer.Close()
+		teardown()
+	}
+}
+
+func TestResources_ExtractArchive_empty_stored_with_descriptor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// The empty stored file with the data descriptor, like the
+	// ones made on the fly, is followed by another entry.
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	if _, err := zw.CreateRaw(&zip.FileHeader{Name: "foo/empty.txt", Method: zip.Store, Flags: 0x8}); err != nil {
+		t.Fatal(err)
+	}
+	w, _ := zw.Create("foo/a.txt")
+	w.Write([]byte("a"))
+	zw.Close()
+	archiveServer := serveArchive(t, buf.Bytes())
+	defer archiveServer.Close()
+
+	dir := t.TempDir()
+	files, err := client.Resources.ExtractArchive(context.Background(), "/foo", dir, nil)
+
+	if err != nil {
+		t.Fatalf("Resources.ExtractArchive returned error %v", err)
+	}
+	if got, want := len(files), 2; got != want {
+		t.Errorf("Extracted %v files, want %v", got, want)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "foo", "a.txt")); err != nil || string(data) != "a" {
+		t.Errorf("File contents are %q, %v, want %q", data, err, "a")
+	}
+}
+
+func TestResources_ExtractArchive_symlinked_folder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+	setup()
+	defer teardown()
+
+	archiveServer := serveArchive(t, zipEntries(t, false, [2]string{"foo/link/evil.txt", "evil"}))
+	defer archiveServer.Close()
+
+	// The link to a folder outside of the destination already exists.
+	parent := t.TempDir()
+	outside := filepath.Join(parent, "outside")
+	dir := filepath.Join(parent, "dir")
+	os.MkdirAll(outside, 0755)
+	os.MkdirAll(filepath.Join(dir, "foo"), 0755)
+	if err := os.Symlink(outside, filepath.Join(dir, "foo", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.Resources.ExtractArchive(context.Background(), "/foo", dir, nil)
+
+	if !errors.Is(err, yadisk.ErrInvalidArchive) {
+		t.Errorf("ExtractArchive returned error %v, want %v", err, yadisk.ErrInvalidArchive)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "evil.txt")); err == nil {
+		t.Errorf("Entry is extracted through the link")
+	}
+}
+
+func TestResources_ExtractArchive_limits(t *testing.T) {
+	entries := [][2]string{
+		{"a.txt", "aaaa"},
+		{"b.txt", "bbbb"},
+		{"c.txt", "cccc"},
+	}
+	tests := []struct {
+		opt  yadisk.ArchiveOptions
+		want error
+	}{
+		{yadisk.ArchiveOptions{MaxFileSize: 4, MaxTotalSize: 12, MaxFiles: 3}, nil},
+		{yadisk.ArchiveOptions{MaxFileSize: 3}, yadisk.ErrArchiveLimit},
+		{yadisk.ArchiveOptions{MaxTotalSize: 10}, yadisk.ErrArchiveLimit},
+		{yadisk.ArchiveOptions{MaxFiles: 2}, yadisk.ErrArchiveLimit},
+	}
+
+	for _, tt := range tests {
+		setup()
+		archiveServer := serveArchive(t, zipEntries(t, false, entries...))
+
+		_, err := client.Resources.ExtractArchive(context.Background(), "/foo", t.TempDir(), &tt.opt)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Options %+v: error is %v, want %v", tt.opt, err, tt.want)
+		}
+
+		archiveServer.Close()
+		teardown()
+	}
+}
+
+func TestResources_ExtractArchive_corrupted(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := zipEntries(t, true, [2]string{"a.txt", "hello"})
+	// Corrupt the stored data after the 30 byte header and the name.
+	data[30+len("a.txt")] = 'j'
+	archiveServer := serveArchive(t, data)
+	defer archiveServer.Close()
+
+	_, err := client.Resources.ExtractArchive(context.Background(), "/foo", t.TempDir(), nil)
+
+	if !errors.Is(err, yadisk.ErrInvalidArchive) {
+		t.Errorf("Error is %v, want %v", err, yadisk.ErrInvalidArchive)
+	}
+}
+
+func TestResources_ExtractArchive_truncated(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := zipEntries(t, false, [2]string{"a.txt", "hello, world"})
+	archiveServer := serveArchive(t, data[:40])
+	defer archiveServer.Close()
+
+	_, err := client.Resources.ExtractArchive(context.Background(), "/foo", t.TempDir(), nil)
+
+	if !errors.Is(err, yadisk.ErrInvalidArchive) {
+		t.Errorf("Error is %v, want %v", err, yadisk.ErrInvalidArchive)
+	}
+}
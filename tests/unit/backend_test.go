@@ -0,0 +1,210 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk/backend"
+	"golang.org/x/net/webdav"
+)
+
+// newTestWebDAV starts an in-memory WebDAV server checking the OAuth
+// token and the "If-None-Match: *" header of the uploads, and returns
+// the backend talking to it.
+func newTestWebDAV() (*backend.WebDAV, *httptest.Server) {
+	dav := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "OAuth ACCESS_TOKEN" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == "PUT" && r.Header.Get("If-None-Match") == "*" {
+			name := strings.TrimPrefix(r.URL.Path, dav.Prefix)
+			if _, err := dav.FileSystem.Stat(r.Context(), name); err == nil {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+		}
+		dav.ServeHTTP(w, r)
+	}))
+
+	b := backend.NewWebDAV("ACCESS_TOKEN")
+	b.BaseURL, _ = url.Parse(srv.URL + "/dav/")
+	return b, srv
+}
+
+// testBackend runs the same checks against any backend.
+func testBackend(t *testing.T, b backend.Backend) {
+	ctx := context.Background()
+	wantErr := func(what string, err, target error) {
+		t.Helper()
+		if !errors.Is(err, target) {
+			t.Errorf("%s returned %v, want %v", what, err, target)
+		}
+	}
+
+	if err := b.Mkdir(ctx, "/docs"); err != nil {
+		t.Fatalf("Mkdir returned error: %v", err)
+	}
+	wantErr("Mkdir of existing folder", b.Mkdir(ctx, "/docs"), fs.ErrExist)
+	wantErr("Mkdir in missing folder", b.Mkdir(ctx, "/missing/docs"), fs.ErrNotExist)
+
+	if err := b.Upload(ctx, "/docs/a.txt", strings.NewReader("hello"), false); err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+	wantErr("Upload without overwrite", b.Upload(ctx, "/docs/a.txt", strings.NewReader("x"), false), fs.ErrExist)
+	if err := b.Upload(ctx, "/docs/a.txt", strings.NewReader("hello, world"), true); err != nil {
+		t.Fatalf("Upload with overwrite returned error: %v", err)
+	}
+
+	entry, err := b.Stat(ctx, "/docs/a.txt")
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if entry.Path != "/docs/a.txt" || entry.Name != "a.txt" || entry.Dir || entry.Size != 12 {
+		t.Errorf("Stat returned %+v", entry)
+	}
+	if entry, err := b.Stat(ctx, "/docs"); err != nil || !entry.Dir {
+		t.Errorf("Stat of folder returned %+v, %v", entry, err)
+	}
+	_, err = b.Stat(ctx, "/missing")
+	wantErr("Stat of missing file", err, fs.ErrNotExist)
+
+	entries, err := b.List(ctx, "/docs")
+	if err != nil || len(entries) != 1 || entries[0].Path != "/docs/a.txt" {
+		t.Errorf("List returned %+v, %v", entries, err)
+	}
+	_, err = b.List(ctx, "/docs/a.txt")
+	wantErr("List of file", err, backend.ErrNotDir)
+
+	var buf bytes.Buffer
+	if err := b.Download(ctx, "/docs/a.txt", &buf); err != nil || buf.String() != "hello, world" {
+		t.Errorf("Download returned %q, %v", buf.String(), err)
+	}
+
+	if err := b.Copy(ctx, "/docs/a.txt", "/docs/b.txt", false); err != nil {
+		t.Fatalf("Copy returned error: %v", err)
+	}
+	wantErr("Copy without overwrite", b.Copy(ctx, "/docs/a.txt", "/docs/b.txt", false), fs.ErrExist)
+	if err := b.Move(ctx, "/docs/b.txt", "/c.txt", false); err != nil {
+		t.Fatalf("Move returned error: %v", err)
+	}
+	_, err = b.Stat(ctx, "/docs/b.txt")
+	wantErr("Stat of moved file", err, fs.ErrNotExist)
+	if entry, err := b.Stat(ctx, "/c.txt"); err != nil || entry.Size != 12 {
+		t.Errorf("Stat of moved file returned %+v, %v", entry, err)
+	}
+
+	if err := b.Delete(ctx, "/docs"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	_, err = b.Stat(ctx, "/docs/a.txt")
+	wantErr("Stat of deleted file", err, fs.ErrNotExist)
+	wantErr("Delete of missing folder", b.Delete(ctx, "/docs"), fs.ErrNotExist)
+}
+
+func TestBackend_REST(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+
+	testBackend(t, backend.NewREST(client))
+}
+
+func TestBackend_WebDAV(t *testing.T) {
+	b, srv := newTestWebDAV()
+	defer srv.Close()
+
+	testBackend(t, b)
+}
+
+func TestBackend_WebDAV_escaped_hrefs(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:">
+<d:response><d:href>%[1]s/dav/</d:href><d:propstat><d:status>HTTP/1.1 200 OK</d:status>
+<d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop></d:propstat></d:response>
+<d:response><d:href>%[1]s/dav/a%%20%%231%%3F%%2520.txt</d:href><d:propstat><d:status>HTTP/1.1 200 OK</d:status>
+<d:prop><d:resourcetype/><d:getcontentlength>1</d:getcontentlength></d:prop></d:propstat></d:response>
+</d:multistatus>`, srv.URL)
+	}))
+	defer srv.Close()
+
+	b := backend.NewWebDAV("ACCESS_TOKEN")
+	b.BaseURL, _ = url.Parse(srv.URL + "/dav/")
+	entries, err := b.List(context.Background(), "/")
+	if want := "/a #1?%20.txt"; err != nil || len(entries) != 1 || entries[0].Path != want {
+		t.Errorf("List returned %+v, %v, want the entry %q", entries, err, want)
+	}
+}
+
+func TestBackend_WebDAV_upload_existing(t *testing.T) {
+	b, srv := newTestWebDAV()
+	defer srv.Close()
+
+	ctx := context.Background()
+	if err := b.Upload(ctx, "/a.txt", strings.NewReader("a"), false); err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+	if err := b.Upload(ctx, "/a.txt", strings.NewReader("b"), false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Upload of existing file returned %v, want %v", err, fs.ErrExist)
+	}
+	var buf bytes.Buffer
+	if err := b.Download(ctx, "/a.txt", &buf); err != nil || buf.String() != "a" {
+		t.Errorf("Download returned %q, %v, want the first upload", buf.String(), err)
+	}
+}
+
+func TestBackend_WebDAV_unauthorized(t *testing.T) {
+	b, srv := newTestWebDAV()
+	defer srv.Close()
+
+	b.AccessToken = "WRONG"
+	if _, err := b.Stat(context.Background(), "/"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Stat with wrong token returned %v, want %v", err, fs.ErrPermission)
+	}
+}
+
+func TestBackend_Router(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	dav, srv := newTestWebDAV()
+	defer srv.Close()
+
+	b := &backend.Router{
+		Default: backend.NewREST(client),
+		Routes:  map[backend.Op]backend.Backend{backend.OpUpload: dav},
+	}
+	ctx := context.Background()
+	if err := b.Upload(ctx, "/a.txt", strings.NewReader("a"), false); err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+	if _, ok := disk.file("/a.txt"); ok {
+		t.Errorf("Upload was sent to the default backend")
+	}
+	if _, err := dav.Stat(ctx, "/a.txt"); err != nil {
+		t.Errorf("Upload wasn't sent to the routed backend: %v", err)
+	}
+	if _, err := b.Stat(ctx, "/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat returned %v, want the default backend's %v", err, fs.ErrNotExist)
+	}
+}
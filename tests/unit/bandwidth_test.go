@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestBandwidthLimiter_WaitN(t *testing.T) {
+	limiter := yadisk.NewBandwidthLimiter(1000, 100)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.WaitN(context.Background(), 100); err != nil {
+			t.Fatalf("WaitN returned error %v", err)
+		}
+	}
+
+	// The first 100 bytes are the burst, the rest take 300ms.
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("WaitN took %v, want at least 250ms", elapsed)
+	}
+}
+
+func TestBandwidthLimiter_WaitN_canceled(t *testing.T) {
+	limiter := yadisk.NewBandwidthLimiter(1, 1)
+	limiter.WaitN(context.Background(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.WaitN(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("WaitN returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBandwidthLimiter_SetLimit(t *testing.T) {
+	limiter := yadisk.NewBandwidthLimiter(1, 1)
+	limiter.WaitN(context.Background(), 1)
+
+	// Removing the limit at runtime unblocks the transfers.
+	limiter.SetLimit(0, 0)
+	start := time.Now()
+	limiter.WaitN(context.Background(), 1<<20)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("WaitN took %v without a limit", elapsed)
+	}
+	if rate, burst := limiter.Limit(); rate != 0 || burst != 0 {
+		t.Errorf("Limit is %v, %v, want 0, 0", rate, burst)
+	}
+}
+
+func TestBandwidthSchedule_At(t *testing.T) {
+	schedule := yadisk.BandwidthSchedule{
+		{Start: 9 * time.Hour, End: 18 * time.Hour, Rate: 100},
+		{Start: 22 * time.Hour, End: 6 * time.Hour, Rate: 1000},
+	}
+
+	tests := []struct {
+		hour int
+		rate int64
+		ok   bool
+	}{
+		{10, 100, true},
+		{18, 0, false},
+		{23, 1000, true},
+		{3, 1000, true},
+		{7, 0, false},
+	}
+	for _, test := range tests {
+		at := time.Date(2017, time.February, 26, test.hour, 0, 0, 0, time.Local)
+		w, ok := schedule.At(at)
+		if ok != test.ok || w.Rate != test.rate {
+			t.Errorf("At(%v:00) = %+v, %v, want rate %v, %v", test.hour, w, ok, test.rate, test.ok)
+		}
+	}
+}
+
+func TestResources_Upload_bandwidth(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+
+	client.Bandwidth = yadisk.NewBandwidthLimiter(1000, 100)
+	data := strings.Repeat("x", 300)
+
+	start := time.Now()
+	response, err := client.Resources.Upload(
+		context.Background(),
+		"/foo.txt",
+		strings.NewReader(data),
+		nil,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.Upload returned error %v, %+v", err, response)
+	}
+	if got := string(uploader.data[1]); got != data {
+		t.Errorf("Uploaded data is %v, want %v", got, data)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Upload took %v, want at least 150ms", elapsed)
+	}
+}
+
+func TestResources_Download_bandwidth(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := bytes.Repeat([]byte("x"), 300)
+	downloader := newFakeDownloader(data)
+	defer downloader.close()
+
+	opt := &yadisk.DownloadOptions{Bandwidth: yadisk.NewBandwidthLimiter(1000, 100)}
+	buf := new(bytes.Buffer)
+
+	start := time.Now()
+	_, err := client.Resources.Download(context.Background(), "/foo.bin", buf, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.Download returned error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Downloaded data is %s, want %s", buf.Bytes(), data)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Download took %v, want at least 150ms", elapsed)
+	}
+}
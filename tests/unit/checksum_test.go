@@ -0,0 +1,287 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// serveUploadedResource registers the metainformation handler
+// reporting the MD5 hash of the uploaded data. If corrupt is true,
+// the hash of other data is reported. It returns the function
+// reporting whether the resource was deleted.
+func serveUploadedResource(t *testing.T, uploader *fakeUploader, corrupt bool) func() bool {
+	deleted := false
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			if got, want := r.URL.Query().Get("permanently"), "true"; got != want {
+				t.Errorf("Request permanently parameter = %v, want %v", got, want)
+			}
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		uploader.mu.Lock()
+		data := uploader.data[uploader.links]
+		uploader.mu.Unlock()
+		if corrupt {
+			data = append([]byte("corrupt"), data...)
+		}
+		sum := md5.Sum(data)
+		fmt.Fprintf(w, `{"path": "disk:/foo.txt", "md5": "%s"}`, hex.EncodeToString(sum[:]))
+	})
+	return func() bool { return deleted }
+}
+
+func TestResources_Upload_verify(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveUploadedResource(t, uploader, false)
+
+	opt := &yadisk.UploadOptions{Verify: yadisk.VerifyAlways}
+	response, err := client.Resources.Upload(
+		context.Background(),
+		"/foo.txt",
+		strings.NewReader("hello"),
+		opt,
+	)
+
+	if err != nil {
+		t.Errorf("Resources.Upload returned error %v, %+v", err, response)
+	}
+}
+
+func TestResources_Upload_verify_mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	deleted := serveUploadedResource(t, uploader, true)
+
+	opt := &yadisk.UploadOptions{
+		Verify:           yadisk.VerifyAlways,
+		DeleteOnMismatch: true,
+	}
+	_, err := client.Resources.Upload(
+		context.Background(),
+		"/foo.txt",
+		strings.NewReader("hello"),
+		opt,
+	)
+
+	if !errors.Is(err, yadisk.ErrChecksumMismatch) {
+		t.Errorf("Resources.Upload returned error %v, want %v", err, yadisk.ErrChecksumMismatch)
+	}
+	var checksumErr *yadisk.ChecksumError
+	if errors.As(err, &checksumErr) && checksumErr.Algorithm != "md5" {
+		t.Errorf("ChecksumError Algorithm is %v, want md5", checksumErr.Algorithm)
+	}
+	if !deleted() {
+		t.Error("Corrupt file should be deleted")
+	}
+}
+
+func TestResources_Upload_verify_mismatch_delete_error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": "DiskResourceLockedError", "description": "Resource is locked."}`)
+			return
+		}
+		fmt.Fprint(w, `{"path": "disk:/foo.txt", "md5": "00000000000000000000000000000000"}`)
+	})
+
+	opt := &yadisk.UploadOptions{
+		Verify:           yadisk.VerifyAlways,
+		DeleteOnMismatch: true,
+	}
+	_, err := client.Resources.Upload(context.Background(), "/foo.txt", strings.NewReader("hello"), opt)
+
+	var checksumErr *yadisk.ChecksumError
+	if !errors.As(err, &checksumErr) {
+		t.Fatalf("Resources.Upload returned error %v, want %v", err, yadisk.ErrChecksumMismatch)
+	}
+	if apiErr, ok := checksumErr.DeleteErr.(*yadisk.APIError); !ok || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("ChecksumError DeleteErr is %v, want the API error", checksumErr.DeleteErr)
+	}
+}
+
+func TestResources_UploadFile_verify_by_default(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	deleted := serveUploadedResource(t, uploader, true)
+
+	localPath := filepath.Join(t.TempDir(), "foo.txt")
+	ioutil.WriteFile(localPath, []byte("hello"), 0644)
+
+	_, err := client.Resources.UploadFile(context.Background(), localPath, "/foo.txt", nil)
+
+	if !errors.Is(err, yadisk.ErrChecksumMismatch) {
+		t.Errorf("Resources.UploadFile returned error %v, want %v", err, yadisk.ErrChecksumMismatch)
+	}
+	if got, want := string(uploader.data[1]), "hello"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+	if deleted() {
+		t.Error("File shouldn't be deleted without DeleteOnMismatch")
+	}
+}
+
+func TestResources_UploadResumable_verify_after_failure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	uploader.breakAfter = 2
+	serveUploadedResource(t, uploader, false)
+
+	data := []byte("0123456789")
+	opt := &yadisk.ResumableUploadOptions{
+		UploadOptions: yadisk.UploadOptions{Verify: yadisk.VerifyAlways},
+		ChunkSize:     4,
+	}
+	_, err := client.Resources.UploadResumable(
+		context.Background(),
+		"/foo.txt",
+		bytes.NewReader(data),
+		int64(len(data)),
+		opt,
+	)
+
+	if err != nil {
+		t.Errorf("Resources.UploadResumable returned error %v", err)
+	}
+}
+
+func TestResources_UploadFile_verify_off(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Metainformation shouldn't be requested without verification")
+	})
+
+	localPath := filepath.Join(t.TempDir(), "foo.txt")
+	ioutil.WriteFile(localPath, []byte("hello"), 0644)
+
+	opt := &yadisk.UploadOptions{Verify: yadisk.VerifyNever}
+	_, err := client.Resources.UploadFile(context.Background(), localPath, "/foo.txt", opt)
+
+	if err != nil {
+		t.Errorf("Resources.UploadFile returned error %v", err)
+	}
+}
+
+func TestResources_Download_verify(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("0123456789"))
+	defer downloader.close()
+
+	opt := &yadisk.DownloadOptions{Verify: yadisk.VerifyAlways}
+	buf := new(bytes.Buffer)
+	_, err := client.Resources.Download(context.Background(), "/foo.bin", buf, opt)
+
+	if err != nil {
+		t.Errorf("Resources.Download returned error %v", err)
+	}
+}
+
+func TestResources_DownloadFile(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := []byte("0123456789")
+	downloader := newFakeDownloader(data)
+	defer downloader.close()
+
+	// The local file is bigger, the rest should be cut off.
+	localPath := filepath.Join(t.TempDir(), "foo.bin")
+	ioutil.WriteFile(localPath, []byte("012345678901234567890"), 0644)
+
+	opt := &yadisk.DownloadOptions{ChunkSize: 4}
+	_, err := client.Resources.DownloadFile(context.Background(), "/foo.bin", localPath, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadFile returned error %v", err)
+	}
+	got, _ := ioutil.ReadFile(localPath)
+	if !bytes.Equal(got, data) {
+		t.Errorf("Downloaded data is %s, want %s", got, data)
+	}
+}
+
+func TestResources_DownloadFile_verify_mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("0123456789"))
+	defer downloader.close()
+	downloader.data = []byte("9876543210")
+
+	localPath := filepath.Join(t.TempDir(), "foo.bin")
+	opt := &yadisk.DownloadOptions{DeleteOnMismatch: true}
+	_, err := client.Resources.DownloadFile(context.Background(), "/foo.bin", localPath, opt)
+
+	if !errors.Is(err, yadisk.ErrChecksumMismatch) {
+		t.Errorf("Resources.DownloadFile returned error %v, want %v", err, yadisk.ErrChecksumMismatch)
+	}
+	if _, err := os.Stat(localPath); !os.IsNotExist(err) {
+		t.Errorf("Corrupt file should be deleted, got %v", err)
+	}
+}
+
+func TestResources_DownloadTo_verify_requires_reader(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("0123456789"))
+	defer downloader.close()
+
+	opt := &yadisk.DownloadOptions{Verify: yadisk.VerifyAlways}
+	dst := writerAtOnly{&memFile{}}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if err == nil {
+		t.Error("Resources.DownloadTo should return error for a destination without ReadAt")
+	}
+}
+
+// writerAtOnly hides all methods except WriteAt.
+type writerAtOnly struct {
+	w interface {
+		WriteAt(p []byte, off int64) (int, error)
+	}
+}
+
+func (w writerAtOnly) WriteAt(p []byte, off int64) (int, error) {
+	return w.w.WriteAt(p, off)
+}
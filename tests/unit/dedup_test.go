@@ -0,0 +1,256 @@
+package unit
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// serveDedupResource registers the metainformation handler reporting
+// the hashes of the last uploaded data, or of the remote data if nothing
+// has been uploaded. Without any data it responds with 404.
+func serveDedupResource(uploader *fakeUploader, remote []byte) {
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		uploader.mu.Lock()
+		data, ok := uploader.data[uploader.links]
+		uploader.mu.Unlock()
+		if !ok {
+			data = remote
+		}
+		if data == nil {
+			http.Error(w, `{"error": "DiskNotFoundError"}`, 404)
+			return
+		}
+
+		md5Sum := md5.Sum(data)
+		sha256Sum := sha256.Sum256(data)
+		fmt.Fprintf(
+			w,
+			`{"path": "disk:/foo.txt", "type": "file", "size": %d, "md5": "%s", "sha256": "%s"}`,
+			len(data),
+			hex.EncodeToString(md5Sum[:]),
+			hex.EncodeToString(sha256Sum[:]),
+		)
+	})
+}
+
+// writeTempFile writes the data to a temporary file and returns its path.
+func writeTempFile(t *testing.T, data string) string {
+	localPath := filepath.Join(t.TempDir(), "foo.txt")
+	ioutil.WriteFile(localPath, []byte(data), 0644)
+	return localPath
+}
+
+func TestResources_UploadFileDedup_skipped(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveDedupResource(uploader, []byte("hello"))
+
+	result, err := client.Resources.UploadFileDedup(
+		context.Background(),
+		writeTempFile(t, "hello"),
+		"/foo.txt",
+		nil,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadFileDedup returned error %v", err)
+	}
+	if got, want := result.Status, yadisk.UploadSkipped; got != want {
+		t.Errorf("Upload status is %v, want %v", got, want)
+	}
+	if uploader.links != 0 {
+		t.Errorf("Upload links requested %v times, want 0", uploader.links)
+	}
+}
+
+func TestResources_UploadFileDedup_transferred(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveDedupResource(uploader, []byte("old"))
+
+	opt := &yadisk.DedupUploadOptions{
+		UploadOptions: yadisk.UploadOptions{Overwrite: true},
+	}
+	result, err := client.Resources.UploadFileDedup(
+		context.Background(),
+		writeTempFile(t, "hello"),
+		"/foo.txt",
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadFileDedup returned error %v", err)
+	}
+	if got, want := result.Status, yadisk.UploadTransferred; got != want {
+		t.Errorf("Upload status is %v, want %v", got, want)
+	}
+	if got, want := string(uploader.data[1]), "hello"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+}
+
+func TestResources_UploadFileDedup_deduplicated(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveDedupResource(uploader, nil)
+	sum := md5.Sum([]byte("hello"))
+	uploader.known[hex.EncodeToString(sum[:])] = []byte("hello")
+
+	opt := &yadisk.DedupUploadOptions{InstantUpload: true}
+	result, err := client.Resources.UploadFileDedup(
+		context.Background(),
+		writeTempFile(t, "hello"),
+		"/foo.txt",
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadFileDedup returned error %v", err)
+	}
+	if got, want := result.Status, yadisk.UploadDeduplicated; got != want {
+		t.Errorf("Upload status is %v, want %v", got, want)
+	}
+	if got, want := uploader.links, 1; got != want {
+		t.Errorf("Upload links requested %v times, want %v", got, want)
+	}
+}
+
+func TestResources_UploadFileDedup_instant_upload_unknown(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveDedupResource(uploader, nil)
+
+	opt := &yadisk.DedupUploadOptions{InstantUpload: true}
+	result, err := client.Resources.UploadFileDedup(
+		context.Background(),
+		writeTempFile(t, "hello"),
+		"/foo.txt",
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadFileDedup returned error %v", err)
+	}
+	if got, want := result.Status, yadisk.UploadTransferred; got != want {
+		t.Errorf("Upload status is %v, want %v", got, want)
+	}
+	if got, want := string(uploader.data[2]), "hello"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+}
+
+func TestUploadStatus_String(t *testing.T) {
+	if got, want := yadisk.UploadDeduplicated.String(), "deduplicated"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func TestResources_UploadFileDedup_instant_upload_mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveDedupResource(uploader, nil)
+	sum := md5.Sum([]byte("hello"))
+	uploader.known[hex.EncodeToString(sum[:])] = []byte("wrong")
+
+	opt := &yadisk.DedupUploadOptions{InstantUpload: true}
+	result, err := client.Resources.UploadFileDedup(
+		context.Background(),
+		writeTempFile(t, "hello"),
+		"/foo.txt",
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadFileDedup returned error %v", err)
+	}
+	if got, want := result.Status, yadisk.UploadTransferred; got != want {
+		t.Errorf("Upload status is %v, want %v", got, want)
+	}
+	if got, want := string(uploader.data[2]), "hello"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+}
+
+func TestResources_UploadFileDedup_instant_upload_error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveDedupResource(uploader, nil)
+	uploader.instantStatus = http.StatusInternalServerError
+
+	opt := &yadisk.DedupUploadOptions{InstantUpload: true}
+	_, err := client.Resources.UploadFileDedup(
+		context.Background(),
+		writeTempFile(t, "hello"),
+		"/foo.txt",
+		opt,
+	)
+
+	var apiErr *yadisk.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Resources.UploadFileDedup returned error %v, want the uploader error", err)
+	}
+	if got, want := uploader.links, 1; got != want {
+		t.Errorf("Upload links requested %v times, want %v", got, want)
+	}
+}
+
+func TestResources_UploadFileDedup_instant_upload_empty(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveDedupResource(uploader, nil)
+	// The uploader accepts the instant upload without the data.
+	sum := md5.Sum([]byte("hello"))
+	uploader.known[hex.EncodeToString(sum[:])] = []byte{}
+
+	opt := &yadisk.DedupUploadOptions{
+		UploadOptions: yadisk.UploadOptions{Verify: yadisk.VerifyNever},
+		InstantUpload: true,
+	}
+	result, err := client.Resources.UploadFileDedup(
+		context.Background(),
+		writeTempFile(t, "hello"),
+		"/foo.txt",
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadFileDedup returned error %v", err)
+	}
+	if got, want := result.Status, yadisk.UploadTransferred; got != want {
+		t.Errorf("Upload status is %v, want %v", got, want)
+	}
+	if got, want := string(uploader.data[2]), "hello"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk/diskfs"
+)
+
+// newTestFS returns the file system over the fake Disk
+// with a few files in the /site folder.
+func newTestFS() (*diskfs.FS, *fakeDisk) {
+	disk := newFakeDisk()
+	modified := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	disk.addFile("/site/index.html", "<h1>Hello</h1>", modified)
+	disk.addFile("/site/css/style.css", "h1 {}", modified)
+	disk.addFile("/site/empty.txt", "", modified)
+	disk.addFile("/site/js/app/main.js", "main()", modified)
+	return diskfs.New(client, "/site"), disk
+}
+
+func TestFS_fstest(t *testing.T) {
+	setup()
+	defer teardown()
+
+	fsys, disk := newTestFS()
+	defer disk.close()
+
+	if err := fstest.TestFS(fsys, "index.html", "css/style.css", "empty.txt", "js/app/main.js"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFS_errors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	fsys, disk := newTestFS()
+	defer disk.close()
+
+	if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open of missing file returned %v, want %v", err, fs.ErrNotExist)
+	}
+	if _, err := fsys.Stat("../etc/passwd"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Stat of invalid name returned %v, want %v", err, fs.ErrInvalid)
+	}
+	if _, err := fsys.ReadDir("index.html"); err == nil {
+		t.Errorf("ReadDir of file returned no error")
+	}
+
+}
+
+func TestFS_permission(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusForbidden, "DiskForbiddenError")
+	})
+	if _, err := diskfs.New(client, "/").Stat("foo"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Stat of forbidden file returned %v, want %v", err, fs.ErrPermission)
+	}
+}
+
+func TestFS_http(t *testing.T) {
+	setup()
+	defer teardown()
+
+	fsys, disk := newTestFS()
+	defer disk.close()
+
+	site := httptest.NewServer(http.FileServer(http.FS(fsys.WithContext(context.Background()))))
+	defer site.Close()
+
+	resp, err := http.Get(site.URL + "/css/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if got, want := string(body), "h1 {}"; got != want {
+		t.Errorf("Served file is %q, want %q", got, want)
+	}
+}
+
+func TestFS_Glob(t *testing.T) {
+	setup()
+	defer teardown()
+
+	fsys, disk := newTestFS()
+	defer disk.close()
+
+	matches, err := fs.Glob(fsys, "*/*.css")
+	if err != nil {
+		t.Fatalf("fs.Glob returned error %v", err)
+	}
+	if len(matches) != 1 || matches[0] != "css/style.css" {
+		t.Errorf("Matches are %v, want [css/style.css]", matches)
+	}
+}
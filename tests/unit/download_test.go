@@ -0,0 +1,351 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// fakeDownloader is a stand-in for the Yandex.Disk downloader.
+// It serves a single file and issues the metainformation
+// and download links for it.
+type fakeDownloader struct {
+	mu sync.Mutex
+
+	// File contents.
+	data []byte
+
+	// File size and hashes reported in the metainformation.
+	size   int
+	md5    string
+	sha256 string
+
+	// Whether the Range header is ignored.
+	noRanges bool
+
+	// Number of requests to fail with 500 before serving.
+	failures int
+
+	// Received Range headers.
+	ranges []string
+
+	// Number of issued download links.
+	links int
+
+	// If positive, the first link expires after serving
+	// that many requests, and responds with 410 Gone.
+	expireAfter int
+	firstServed int
+
+	server *httptest.Server
+}
+
+// newFakeDownloader starts the fake downloader server and registers
+// the metainformation and download link handlers on mux.
+// The downloader should be closed after the test.
+func newFakeDownloader(data []byte) *fakeDownloader {
+	md5Sum := md5.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+	d := &fakeDownloader{
+		data:   data,
+		size:   len(data),
+		md5:    hex.EncodeToString(md5Sum[:]),
+		sha256: hex.EncodeToString(sha256Sum[:]),
+	}
+	d.server = httptest.NewServer(http.HandlerFunc(d.serveDownload))
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(
+			w,
+			`{"path": "disk:/foo.bin", "type": "file", "size": %d, "revision": 1, "md5": "%s", "sha256": "%s"}`,
+			d.size,
+			d.md5,
+			d.sha256,
+		)
+	})
+	mux.HandleFunc("/v1/disk/resources/download", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		d.links++
+		n := d.links
+		d.mu.Unlock()
+		fmt.Fprintf(
+			w,
+			`{"href": "%s/download/foo.bin?link=%d", "method": "GET", "templated": false}`,
+			d.server.URL,
+			n,
+		)
+	})
+	return d
+}
+
+// close shuts down the downloader server.
+func (d *fakeDownloader) close() {
+	d.server.Close()
+}
+
+func (d *fakeDownloader) serveDownload(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	if d.expireAfter > 0 && r.URL.Query().Get("link") == "1" {
+		if d.firstServed >= d.expireAfter {
+			d.mu.Unlock()
+			http.Error(w, "link expired", http.StatusGone)
+			return
+		}
+		d.firstServed++
+	}
+	d.ranges = append(d.ranges, r.Header.Get("Range"))
+	if d.failures > 0 {
+		d.failures--
+		d.mu.Unlock()
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "internal error", 500)
+		return
+	}
+	d.mu.Unlock()
+
+	if d.noRanges {
+		r.Header.Del("Range")
+	}
+	http.ServeContent(w, r, "foo.bin", time.Time{}, bytes.NewReader(d.data))
+}
+
+// sortedRanges returns the received Range headers in sorted order.
+func (d *fakeDownloader) sortedRanges() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ranges := append([]string(nil), d.ranges...)
+	sort.Strings(ranges)
+	return strings.Join(ranges, ",")
+}
+
+func TestResources_Download(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("hello"))
+	defer downloader.close()
+
+	buf := new(bytes.Buffer)
+	response, err := client.Resources.Download(context.Background(), "/foo.bin", buf, nil)
+
+	if err != nil {
+		t.Fatalf("Resources.Download returned error %v, %+v", err, response)
+	}
+	if got, want := buf.String(), "hello"; got != want {
+		t.Errorf("Downloaded data is %v, want %v", got, want)
+	}
+}
+
+func TestResources_DownloadTo(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := []byte("0123456789")
+	downloader := newFakeDownloader(data)
+	defer downloader.close()
+
+	dst, _ := os.Create(filepath.Join(t.TempDir(), "foo.bin"))
+	defer dst.Close()
+
+	opt := &yadisk.DownloadOptions{ChunkSize: 3, Concurrency: 2}
+	resource, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadTo returned error %v", err)
+	}
+	if got, want := resource.Size, uint(10); got != want {
+		t.Errorf("Returned resource Size is %v, want %v", got, want)
+	}
+	got, _ := ioutil.ReadFile(dst.Name())
+	if !bytes.Equal(got, data) {
+		t.Errorf("Downloaded data is %s, want %s", got, data)
+	}
+	wantRanges := "bytes=0-2,bytes=3-5,bytes=6-8,bytes=9-9"
+	if got := downloader.sortedRanges(); got != wantRanges {
+		t.Errorf("Range headers are %v, want %v", got, wantRanges)
+	}
+}
+
+func TestResources_DownloadTo_retries_chunk(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := []byte("0123456789")
+	downloader := newFakeDownloader(data)
+	downloader.failures = 1
+	defer downloader.close()
+
+	dst, _ := os.Create(filepath.Join(t.TempDir(), "foo.bin"))
+	defer dst.Close()
+
+	opt := &yadisk.DownloadOptions{ChunkSize: 5}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadTo returned error %v", err)
+	}
+	got, _ := ioutil.ReadFile(dst.Name())
+	if !bytes.Equal(got, data) {
+		t.Errorf("Downloaded data is %s, want %s", got, data)
+	}
+}
+
+func TestResources_DownloadTo_expired_link(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := []byte("0123456789")
+	downloader := newFakeDownloader(data)
+	downloader.expireAfter = 2
+	defer downloader.close()
+
+	dst, _ := os.Create(filepath.Join(t.TempDir(), "foo.bin"))
+	defer dst.Close()
+
+	opt := &yadisk.DownloadOptions{ChunkSize: 3, Concurrency: 1}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadTo returned error %v", err)
+	}
+	got, _ := ioutil.ReadFile(dst.Name())
+	if !bytes.Equal(got, data) {
+		t.Errorf("Downloaded data is %s, want %s", got, data)
+	}
+	if got, want := downloader.links, 2; got != want {
+		t.Errorf("Download links requested %v times, want %v", got, want)
+	}
+	// The chunks downloaded with the first link aren't requested again.
+	wantRanges := "bytes=0-2,bytes=3-5,bytes=6-8,bytes=9-9"
+	if got := downloader.sortedRanges(); got != wantRanges {
+		t.Errorf("Range headers are %v, want %v", got, wantRanges)
+	}
+}
+
+// failingWriterAt fails every write like a full disk.
+type failingWriterAt struct{}
+
+func (failingWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return 0, syscall.ENOSPC
+}
+
+func TestResources_DownloadTo_write_error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("0123456789"))
+	defer downloader.close()
+
+	opt := &yadisk.DownloadOptions{ChunkSize: 10, MaxRetries: 3, Verify: yadisk.VerifyNever}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", failingWriterAt{}, opt)
+
+	if !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Resources.DownloadTo returned error %v, want %v", err, syscall.ENOSPC)
+	}
+	// The write error isn't fixed by requesting the data again.
+	if got := downloader.sortedRanges(); got != "bytes=0-9" {
+		t.Errorf("Range headers are %v, want a single request", got)
+	}
+}
+
+func TestResources_DownloadTo_without_ranges(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := []byte("0123456789")
+	downloader := newFakeDownloader(data)
+	downloader.noRanges = true
+	defer downloader.close()
+
+	dst, _ := os.Create(filepath.Join(t.TempDir(), "foo.bin"))
+	defer dst.Close()
+
+	opt := &yadisk.DownloadOptions{ChunkSize: 3}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadTo returned error %v", err)
+	}
+	got, _ := ioutil.ReadFile(dst.Name())
+	if !bytes.Equal(got, data) {
+		t.Errorf("Downloaded data is %s, want %s", got, data)
+	}
+	if got, want := downloader.sortedRanges(), ",bytes=0-2"; got != want {
+		t.Errorf("Range headers are %v, want %v", got, want)
+	}
+}
+
+func TestResources_DownloadTo_resume(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := []byte("0123456789")
+	downloader := newFakeDownloader(data)
+	defer downloader.close()
+
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "foo.bin.state")
+	ioutil.WriteFile(
+		statePath,
+		[]byte(`{"path": "disk:/foo.bin", "revision": 1, "size": 10, "chunk_size": 4, "done": [0, 2]}`),
+		0644,
+	)
+	dst, _ := os.Create(filepath.Join(dir, "foo.bin"))
+	defer dst.Close()
+	dst.WriteAt([]byte("0123"), 0)
+	dst.WriteAt([]byte("89"), 8)
+
+	opt := &yadisk.DownloadOptions{ChunkSize: 4, StatePath: statePath}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadTo returned error %v", err)
+	}
+	got, _ := ioutil.ReadFile(dst.Name())
+	if !bytes.Equal(got, data) {
+		t.Errorf("Downloaded data is %s, want %s", got, data)
+	}
+	if got, want := downloader.sortedRanges(), "bytes=4-7"; got != want {
+		t.Errorf("Range headers are %v, want %v", got, want)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("State file should be removed after the download, got %v", err)
+	}
+}
+
+func TestResources_DownloadTo_size_mismatch(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("0123456789"))
+	defer downloader.close()
+	downloader.noRanges = true
+	downloader.data = []byte("01234")
+
+	dst, _ := os.Create(filepath.Join(t.TempDir(), "foo.bin"))
+	defer dst.Close()
+
+	opt := &yadisk.DownloadOptions{MaxRetries: 1}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if !errors.Is(err, yadisk.ErrSizeMismatch) {
+		t.Errorf("Resources.DownloadTo returned error %v, want %v", err, yadisk.ErrSizeMismatch)
+	}
+}
@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestResources_DownloadDir(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	modified := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	disk.addFile("/backup/a.txt", "a", modified)
+	disk.addFile("/backup/sub/b.txt", "b", modified)
+	disk.addFile("/backup/sub/deep/empty.txt", "", modified)
+
+	dir := t.TempDir()
+	report, err := client.Resources.DownloadDir(context.Background(), "/backup", dir, nil)
+	if err != nil {
+		t.Fatalf("Resources.DownloadDir returned error %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"a.txt":              "a",
+		"sub/b.txt":          "b",
+		"sub/deep/empty.txt": "",
+	} {
+		localPath := filepath.Join(dir, filepath.FromSlash(name))
+		data, err := ioutil.ReadFile(localPath)
+		if err != nil || string(data) != want {
+			t.Errorf("File %v contents are %q, %v, want %q", name, data, err, want)
+		}
+		info, err := os.Stat(localPath)
+		if err != nil || !info.ModTime().Equal(modified) {
+			t.Errorf("File %v modification time is wrong: %v", name, info.ModTime())
+		}
+		if runtime.GOOS != "windows" && err == nil && info.Mode().Perm() != 0644 {
+			t.Errorf("File %v mode is %v, want %v", name, info.Mode().Perm(), os.FileMode(0644))
+		}
+	}
+	if got, want := len(report.Files), 3; got != want {
+		t.Errorf("Report has %v files, want %v", got, want)
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Errorf("Failed files are %+v, want none", failed)
+	}
+
+	// No temporary files are left.
+	matches, _ := filepath.Glob(filepath.Join(dir, ".*.part"))
+	if len(matches) != 0 {
+		t.Errorf("Temporary files are left: %v", matches)
+	}
+}
+
+func TestResources_DownloadDir_pages(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	for i := 0; i < 250; i++ {
+		disk.addFile(fmt.Sprintf("/backup/%03d.txt", i), "data", time.Time{})
+	}
+
+	dir := t.TempDir()
+	report, err := client.Resources.DownloadDir(context.Background(), "/backup", dir, nil)
+	if err != nil {
+		t.Fatalf("Resources.DownloadDir returned error %v", err)
+	}
+
+	if got, want := len(report.Files), 250; got != want {
+		t.Errorf("Report has %v files, want %v", got, want)
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	if got, want := len(entries), 250; got != want {
+		t.Errorf("Downloaded %v files, want %v", got, want)
+	}
+}
+
+func TestResources_DownloadDir_skip_same(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/backup/same.txt", "same", time.Time{})
+	disk.addFile("/backup/changed.txt", "new", time.Time{})
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"same.txt":    "same",
+		"changed.txt": "old",
+	})
+
+	report, err := client.Resources.DownloadDir(context.Background(), "/backup", dir, nil)
+	if err != nil {
+		t.Fatalf("Resources.DownloadDir returned error %v", err)
+	}
+
+	skipped := make(map[string]bool)
+	for _, f := range report.Files {
+		skipped[filepath.Base(f.LocalPath)] = f.Skipped
+	}
+	if !skipped["same.txt"] || skipped["changed.txt"] {
+		t.Errorf("Skipped files are %v, want only same.txt", skipped)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "changed.txt")); string(data) != "new" {
+		t.Errorf("Changed file contents are %q, want %q", data, "new")
+	}
+}
+
+func TestResources_DownloadDir_file_failure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/backup/a.txt", "a", time.Time{})
+	disk.addFile("/backup/b.txt", "b", time.Time{})
+
+	// The local file can't be replaced with a folder in its place.
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt/foo": "foo",
+	})
+
+	report, err := client.Resources.DownloadDir(context.Background(), "/backup", dir, nil)
+	if err != nil {
+		t.Fatalf("Resources.DownloadDir returned error %v", err)
+	}
+
+	failed := report.Failed()
+	if len(failed) != 1 || filepath.Base(failed[0].LocalPath) != "a.txt" {
+		t.Errorf("Failed files are %+v, want a.txt", failed)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "b.txt")); string(data) != "b" {
+		t.Errorf("File b.txt contents are %q, want %q", data, "b")
+	}
+}
+
+func TestResources_DownloadDir_not_folder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/backup", "a", time.Time{})
+
+	_, err := client.Resources.DownloadDir(context.Background(), "/backup", t.TempDir(), &yadisk.DownloadDirOptions{})
+
+	if err == nil {
+		t.Errorf("Resources.DownloadDir returned no error for a file")
+	}
+}
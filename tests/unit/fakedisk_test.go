@@ -0,0 +1,334 @@
+package unit
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeDisk is an in-memory stand-in for Yandex.Disk. It serves the
+// metainformation, folder creation, deletion, upload and download
+// links, moves and copies on mux, and the uploads and downloads on
+// its own server. Paths are kept cleaned and without the "disk:" prefix.
+type fakeDisk struct {
+	mu sync.Mutex
+
+	files    map[string][]byte
+	dirs     map[string]bool
+	modified map[string]time.Time
+
+	// Number of uploads received.
+	uploads int
+
+	// Paths of the folders listed with the limit of 100 resources.
+	listed []string
+
+	server *httptest.Server
+}
+
+// newFakeDisk starts the fake Disk with the root folder only.
+// It should be closed after the test.
+func newFakeDisk() *fakeDisk {
+	d := &fakeDisk{
+		files:    make(map[string][]byte),
+		dirs:     map[string]bool{"/": true},
+		modified: make(map[string]time.Time),
+	}
+	d.server = httptest.NewServer(http.HandlerFunc(d.serveContent))
+	mux.HandleFunc("/v1/disk/resources", d.serveResources)
+	mux.HandleFunc("/v1/disk/resources/upload", d.serveUploadLink)
+	mux.HandleFunc("/v1/disk/resources/download", d.serveDownloadLink)
+	mux.HandleFunc("/v1/disk/resources/move", d.serveRelocate)
+	mux.HandleFunc("/v1/disk/resources/copy", d.serveRelocate)
+	return d
+}
+
+// close shuts down the content server.
+func (d *fakeDisk) close() {
+	d.server.Close()
+}
+
+// addFile stores the file, creating the parent folders.
+func (d *fakeDisk) addFile(name string, data string, modified time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	name = cleanDiskPath(name)
+	for dir := path.Dir(name); !d.dirs[dir]; dir = path.Dir(dir) {
+		d.dirs[dir] = true
+	}
+	d.files[name] = []byte(data)
+	d.modified[name] = modified
+}
+
+// file returns the contents of the file.
+func (d *fakeDisk) file(name string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data, ok := d.files[cleanDiskPath(name)]
+	return string(data), ok
+}
+
+// dir reports whether the folder exists.
+func (d *fakeDisk) dir(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dirs[cleanDiskPath(name)]
+}
+
+// listedDirs returns the paths of the listed folders in the request order.
+func (d *fakeDisk) listedDirs() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string(nil), d.listed...)
+}
+
+// cleanDiskPath strips the namespace and cleans the path.
+func cleanDiskPath(p string) string {
+	return path.Clean("/" + strings.TrimPrefix(p, "disk:"))
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "description": code})
+}
+
+// resource returns the metainformation of the file or folder.
+// It must be called with the mutex held.
+func (d *fakeDisk) resource(name string) map[string]interface{} {
+	r := map[string]interface{}{
+		"path":     "disk:" + name,
+		"name":     path.Base(name),
+		"modified": d.modified[name].Format(time.RFC3339),
+	}
+	if data, ok := d.files[name]; ok {
+		md5Sum := md5.Sum(data)
+		sha256Sum := sha256.Sum256(data)
+		r["type"] = "file"
+		r["size"] = len(data)
+		r["md5"] = hex.EncodeToString(md5Sum[:])
+		r["sha256"] = hex.EncodeToString(sha256Sum[:])
+		if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+			r["mime_type"] = t
+			r["media_type"] = strings.SplitN(t, "/", 2)[0]
+		}
+	} else {
+		r["type"] = "dir"
+	}
+	return r
+}
+
+// children returns the sorted names of the folder contents.
+// It must be called with the mutex held.
+func (d *fakeDisk) children(name string) []string {
+	var children []string
+	for _, m := range []map[string]bool{d.dirs, d.fileSet()} {
+		for p := range m {
+			if p != "/" && path.Dir(p) == name {
+				children = append(children, p)
+			}
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func (d *fakeDisk) fileSet() map[string]bool {
+	set := make(map[string]bool, len(d.files))
+	for p := range d.files {
+		set[p] = true
+	}
+	return set
+}
+
+func (d *fakeDisk) serveResources(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	name := cleanDiskPath(r.URL.Query().Get("path"))
+	_, isFile := d.files[name]
+	exists := isFile || d.dirs[name]
+
+	switch r.Method {
+	case "GET":
+		if !exists {
+			writeAPIError(w, http.StatusNotFound, "DiskNotFoundError")
+			return
+		}
+		resource := d.resource(name)
+		if !isFile {
+			limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+			if err != nil {
+				limit = 20
+			}
+			if limit == 100 {
+				d.listed = append(d.listed, name)
+			}
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			children := d.children(name)
+			items := []interface{}{}
+			for i := offset; i < len(children) && i < offset+limit; i++ {
+				items = append(items, d.resource(children[i]))
+			}
+			resource["_embedded"] = map[string]interface{}{
+				"path":   "disk:" + name,
+				"limit":  limit,
+				"offset": offset,
+				"total":  len(children),
+				"items":  items,
+			}
+		}
+		json.NewEncoder(w).Encode(resource)
+	case "PUT":
+		switch {
+		case isFile:
+			writeAPIError(w, http.StatusConflict, "DiskResourceAlreadyExistsError")
+		case exists:
+			writeAPIError(w, http.StatusConflict, "DiskPathPointsToExistentDirectoryError")
+		case !d.dirs[path.Dir(name)]:
+			writeAPIError(w, http.StatusConflict, "DiskPathDoesntExistsError")
+		default:
+			d.dirs[name] = true
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"href": "disk:" + name, "method": "GET"})
+		}
+	case "DELETE":
+		if !exists {
+			writeAPIError(w, http.StatusNotFound, "DiskNotFoundError")
+			return
+		}
+		for p := range d.files {
+			if p == name || strings.HasPrefix(p, name+"/") {
+				delete(d.files, p)
+			}
+		}
+		for p := range d.dirs {
+			if p == name || strings.HasPrefix(p, name+"/") {
+				delete(d.dirs, p)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// serveRelocate moves or copies the resource, depending on the URL path.
+func (d *fakeDisk) serveRelocate(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	from := cleanDiskPath(r.URL.Query().Get("from"))
+	to := cleanDiskPath(r.URL.Query().Get("path"))
+	_, isFile := d.files[from]
+	_, toFile := d.files[to]
+	switch {
+	case !isFile && !d.dirs[from]:
+		writeAPIError(w, http.StatusNotFound, "DiskNotFoundError")
+		return
+	case (toFile || d.dirs[to]) && r.URL.Query().Get("overwrite") != "true":
+		writeAPIError(w, http.StatusConflict, "DiskResourceAlreadyExistsError")
+		return
+	case !d.dirs[path.Dir(to)]:
+		writeAPIError(w, http.StatusConflict, "DiskPathDoesntExistsError")
+		return
+	}
+
+	move := strings.HasSuffix(r.URL.Path, "/move")
+	delete(d.files, to)
+	files := make(map[string][]byte)
+	for p, data := range d.files {
+		if p == from || strings.HasPrefix(p, from+"/") {
+			files[to+strings.TrimPrefix(p, from)] = data
+			if move {
+				delete(d.files, p)
+			}
+		}
+	}
+	dirs := make(map[string]bool)
+	for p := range d.dirs {
+		if p == from || strings.HasPrefix(p, from+"/") {
+			dirs[to+strings.TrimPrefix(p, from)] = true
+			if move {
+				delete(d.dirs, p)
+			}
+		}
+	}
+	for p, data := range files {
+		d.files[p] = data
+		d.modified[p] = time.Now()
+	}
+	for p := range dirs {
+		d.dirs[p] = true
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"href": "disk:" + to, "method": "GET"})
+}
+
+func (d *fakeDisk) serveUploadLink(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	name := cleanDiskPath(r.URL.Query().Get("path"))
+	switch {
+	case d.dirs[name]:
+		writeAPIError(w, http.StatusConflict, "DiskResourceAlreadyExistsError")
+		return
+	case d.files[name] != nil && r.URL.Query().Get("overwrite") != "true":
+		writeAPIError(w, http.StatusConflict, "DiskResourceAlreadyExistsError")
+		return
+	case !d.dirs[path.Dir(name)]:
+		writeAPIError(w, http.StatusConflict, "DiskPathDoesntExistsError")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"href":   d.server.URL + "/upload?" + url.Values{"path": {name}}.Encode(),
+		"method": "PUT",
+	})
+}
+
+func (d *fakeDisk) serveDownloadLink(w http.ResponseWriter, r *http.Request) {
+	name := cleanDiskPath(r.URL.Query().Get("path"))
+	json.NewEncoder(w).Encode(map[string]string{
+		"href":   d.server.URL + "/download?" + url.Values{"path": {name}}.Encode(),
+		"method": "GET",
+	})
+}
+
+// serveContent receives the uploads and serves the downloads.
+func (d *fakeDisk) serveContent(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("path")
+	switch r.URL.Path {
+	case "/upload":
+		data, _ := io.ReadAll(r.Body)
+		d.mu.Lock()
+		d.files[name] = data
+		d.modified[name] = time.Now()
+		d.uploads++
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	case "/download":
+		d.mu.Lock()
+		data, ok := d.files[name]
+		d.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, path.Base(name), time.Time{}, bytes.NewReader(data))
+	}
+}
@@ -0,0 +1,236 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestResources_Create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+
+	w, err := client.Resources.Create(context.Background(), "/foo.txt", nil)
+	if err != nil {
+		t.Fatalf("Resources.Create returned error %v", err)
+	}
+	io.WriteString(w, "hello, ")
+	io.WriteString(w, "world")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error %v", err)
+	}
+
+	if got, want := string(uploader.data[1]), "hello, world"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+}
+
+func TestResources_Create_link_error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/resources/upload", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error": "DiskResourceAlreadyExistsError"}`))
+	})
+
+	_, err := client.Resources.Create(context.Background(), "/foo.txt", nil)
+
+	var apiErr *yadisk.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("Resources.Create returned error %v, want the API error with 409", err)
+	}
+}
+
+func TestResources_Create_upload_error(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	uploader.expired[1] = true
+
+	w, err := client.Resources.Create(context.Background(), "/foo.txt", nil)
+	if err != nil {
+		t.Fatalf("Resources.Create returned error %v", err)
+	}
+	io.WriteString(w, "hello")
+	err = w.Close()
+
+	var apiErr *yadisk.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusGone {
+		t.Errorf("Close returned error %v, want the API error with 410", err)
+	}
+}
+
+func TestResources_Open(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("hello, world"))
+	defer downloader.close()
+
+	f, err := client.Resources.Open(context.Background(), "/foo.bin")
+	if err != nil {
+		t.Fatalf("Resources.Open returned error %v", err)
+	}
+	defer f.Close()
+
+	if got, want := f.Size(), int64(12); got != want {
+		t.Errorf("Size() = %v, want %v", got, want)
+	}
+
+	p := make([]byte, 5)
+	n, err := f.ReadAt(p, 7)
+	if err != nil {
+		t.Fatalf("ReadAt returned error %v", err)
+	}
+	if got, want := string(p[:n]), "world"; got != want {
+		t.Errorf("ReadAt read %v, want %v", got, want)
+	}
+
+	n, err = f.ReadAt(p, 10)
+	if err != io.EOF || string(p[:n]) != "ld" {
+		t.Errorf("ReadAt at the end = %q, %v, want %q, EOF", p[:n], err, "ld")
+	}
+
+	if _, err := f.Seek(-5, io.SeekEnd); err != nil {
+		t.Fatalf("Seek returned error %v", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("Read returned error %v", err)
+	}
+	if got, want := string(data), "world"; got != want {
+		t.Errorf("Read data is %v, want %v", got, want)
+	}
+
+	if got, want := strings.Join(downloader.ranges, ","), "bytes=7-11,bytes=10-11,bytes=7-11"; got != want {
+		t.Errorf("Requested ranges are %v, want %v", got, want)
+	}
+}
+
+func TestResources_Open_read_ahead(t *testing.T) {
+	setup()
+	defer teardown()
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	downloader := newFakeDownloader(data)
+	defer downloader.close()
+
+	f, err := client.Resources.Open(context.Background(), "/foo.bin")
+	if err != nil {
+		t.Fatalf("Resources.Open returned error %v", err)
+	}
+	defer f.Close()
+
+	// Small reads are served from a single request.
+	p := make([]byte, 10)
+	for i := 0; i < 100; i++ {
+		if _, err := io.ReadFull(f, p); err != nil {
+			t.Fatalf("Read returned error %v", err)
+		}
+	}
+	if got, want := len(downloader.ranges), 1; got != want {
+		t.Errorf("Made %v requests, want %v", got, want)
+	}
+}
+
+func TestResources_Open_without_ranges(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("hello, world"))
+	defer downloader.close()
+	downloader.noRanges = true
+
+	f, err := client.Resources.Open(context.Background(), "/foo.bin")
+	if err != nil {
+		t.Fatalf("Resources.Open returned error %v", err)
+	}
+	defer f.Close()
+
+	p := make([]byte, 5)
+	if _, err := f.ReadAt(p, 7); err != nil {
+		t.Fatalf("ReadAt returned error %v", err)
+	}
+	if got, want := string(p), "world"; got != want {
+		t.Errorf("ReadAt read %v, want %v", got, want)
+	}
+}
+
+func TestResources_Open_zip(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// Build the archive with the remote file writer.
+	uploader := newFakeUploader()
+	defer uploader.close()
+
+	w, err := client.Resources.Create(context.Background(), "/foo.zip", nil)
+	if err != nil {
+		t.Fatalf("Resources.Create returned error %v", err)
+	}
+	zw := zip.NewWriter(w)
+	fw, _ := zw.Create("hello.txt")
+	io.WriteString(fw, "hello, world")
+	zw.Close()
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error %v", err)
+	}
+
+	// Read it back with the remote file reader.
+	teardown()
+	setup()
+	downloader := newFakeDownloader(uploader.data[1])
+	defer downloader.close()
+
+	f, err := client.Resources.Open(context.Background(), "/foo.zip")
+	if err != nil {
+		t.Fatalf("Resources.Open returned error %v", err)
+	}
+	defer f.Close()
+
+	zr, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		t.Fatalf("zip.NewReader returned error %v", err)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("Open returned error %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	if got, want := string(data), "hello, world"; got != want {
+		t.Errorf("Archived data is %v, want %v", got, want)
+	}
+}
+
+func TestResources_Open_closed(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("hello"))
+	defer downloader.close()
+
+	f, err := client.Resources.Open(context.Background(), "/foo.bin")
+	if err != nil {
+		t.Fatalf("Resources.Open returned error %v", err)
+	}
+	f.Close()
+
+	if _, err := f.Read(make([]byte, 5)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Read returned error %v, want %v", err, os.ErrClosed)
+	}
+}
@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// globPaths returns the paths of the resources matching the pattern.
+func globPaths(t *testing.T, pattern string) []string {
+	matches, err := client.Resources.Glob(context.Background(), pattern)
+	if err != nil {
+		t.Fatalf("Glob(%q) returned error: %v", pattern, err)
+	}
+	paths := []string{}
+	for _, m := range matches {
+		paths = append(paths, m.Path.String())
+	}
+	return paths
+}
+
+func TestResources_Glob(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	now := time.Now()
+	for _, name := range []string{
+		"/photos/2019/a.jpg",
+		"/photos/2020/b.jpg",
+		"/photos/2020/c.png",
+		"/photos/2020/trip/d.jpg",
+		"/photos/2021/e.jpg",
+		"/docs/f.jpg",
+	} {
+		disk.addFile(name, "x", now)
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"/photos/20??/*.jpg", []string{"disk:/photos/2019/a.jpg", "disk:/photos/2020/b.jpg", "disk:/photos/2021/e.jpg"}},
+		{"/photos/20[2-9][^1]/*", []string{"disk:/photos/2020/b.jpg", "disk:/photos/2020/c.png", "disk:/photos/2020/trip"}},
+		{"/photos/**/*.jpg", []string{"disk:/photos/2019/a.jpg", "disk:/photos/2020/b.jpg", "disk:/photos/2020/trip/d.jpg", "disk:/photos/2021/e.jpg"}},
+		{"disk:/**/*.jpg", []string{"disk:/docs/f.jpg", "disk:/photos/2019/a.jpg", "disk:/photos/2020/b.jpg", "disk:/photos/2020/trip/d.jpg", "disk:/photos/2021/e.jpg"}},
+		{"/photos/2020/**", []string{"disk:/photos/2020/b.jpg", "disk:/photos/2020/c.png", "disk:/photos/2020/trip", "disk:/photos/2020/trip/d.jpg"}},
+		{"/*/2020/trip", []string{"disk:/photos/2020/trip"}},
+		{"/photos/2020/b.jpg", []string{"disk:/photos/2020/b.jpg"}},
+		{"/photos/2022/*", []string{}},
+		{"/missing/*.jpg", []string{}},
+	}
+	for _, tt := range tests {
+		if got := globPaths(t, tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Glob(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+
+	if _, err := client.Resources.Glob(context.Background(), "/photos/[a-"); err != path.ErrBadPattern {
+		t.Errorf("Glob with bad pattern returned %v, want %v", err, path.ErrBadPattern)
+	}
+}
+
+func TestResources_Glob_lists_needed_folders(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		disk.addFile(fmt.Sprintf("/projects/p%d/src/main.go", i), "x", now)
+		disk.addFile(fmt.Sprintf("/projects/p%d/vendor/lib/lib.go", i), "x", now)
+	}
+	disk.addFile("/other/big/file.go", "x", now)
+
+	got := globPaths(t, "/projects/*/src/*.go")
+	if len(got) != 3 {
+		t.Errorf("Glob returned %v, want 3 files", got)
+	}
+	listed := disk.listedDirs()
+	sort.Strings(listed)
+	want := []string{"/projects", "/projects/p0/src", "/projects/p1/src", "/projects/p2/src"}
+	if !reflect.DeepEqual(listed, want) {
+		t.Errorf("Glob listed %v, want %v", listed, want)
+	}
+}
+
+func TestResources_Glob_namespaces(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var paths []string
+	mux.HandleFunc("/v1/disk/trash/resources", func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Query().Get("path"))
+		fmt.Fprint(w, `{"path": "trash:/", "type": "dir", "_embedded": {"total": 2, "items": [
+			{"name": "report.doc", "path": "trash:/report.doc_1a2b", "type": "file"},
+			{"name": "photo.jpg", "path": "trash:/photo.jpg_3c4d", "type": "file"}
+		]}}`)
+	})
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Query().Get("path"))
+		fmt.Fprint(w, `{"path": "app:/cache", "type": "dir", "_embedded": {"total": 1, "items": [
+			{"name": "a.tmp", "path": "app:/cache/a.tmp", "type": "file"}
+		]}}`)
+	})
+
+	if got, want := globPaths(t, "trash:/*.doc"), []string{"trash:/report.doc_1a2b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Glob in the Trash = %v, want %v", got, want)
+	}
+	if got, want := globPaths(t, "app:/cache/*.tmp"), []string{"app:/cache/a.tmp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Glob in the app folder = %v, want %v", got, want)
+	}
+	if want := []string{"trash:/", "trash:/", "app:/cache", "app:/cache"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Glob requested paths %v, want %v", paths, want)
+	}
+}
+
+func TestResources_Glob_trash_literals(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var paths []string
+	mux.HandleFunc("/v1/disk/trash/resources", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		paths = append(paths, path)
+		switch path {
+		case "trash:/":
+			fmt.Fprint(w, `{"path": "trash:/", "type": "dir", "_embedded": {"total": 2, "items": [
+				{"name": "report.doc", "path": "trash:/report.doc_1a2b", "type": "file"},
+				{"name": "Old", "path": "trash:/Old_3c4d", "type": "dir"}
+			]}}`)
+		case "trash:/Old_3c4d":
+			fmt.Fprint(w, `{"path": "trash:/Old_3c4d", "type": "dir", "_embedded": {"total": 2, "items": [
+				{"name": "a.txt", "path": "trash:/Old_3c4d/a.txt", "type": "file"},
+				{"name": "b.doc", "path": "trash:/Old_3c4d/b.doc", "type": "file"}
+			]}}`)
+		default:
+			http.Error(w, `{"error": "DiskNotFoundError"}`, http.StatusNotFound)
+		}
+	})
+
+	if got, want := globPaths(t, "trash:/report.doc"), []string{"trash:/report.doc_1a2b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Glob of a literal Trash name = %v, want %v", got, want)
+	}
+	if got, want := globPaths(t, "trash:/Old/*.txt"), []string{"trash:/Old_3c4d/a.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Glob in a literal Trash folder = %v, want %v", got, want)
+	}
+	for _, p := range paths {
+		if p != "trash:/" && p != "trash:/Old_3c4d" {
+			t.Errorf("Glob requested the Trash path %q", p)
+		}
+	}
+}
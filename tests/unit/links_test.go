@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestLink_Expand(t *testing.T) {
+	values := map[string]string{
+		"var":   "value",
+		"hello": "Hello World!",
+		"path":  "/foo/bar",
+		"x":     "1024",
+		"y":     "768",
+		"empty": "",
+	}
+
+	// Examples from RFC 6570.
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{var}", "value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"here?ref={+path}", "here?ref=/foo/bar"},
+		{"X{#var}", "X#value"},
+		{"map?{x,y}", "map?1024,768"},
+		{"{+x,hello,y}", "1024,Hello%20World!,768"},
+		{"X{.var}", "X.value"},
+		{"{/var,x}/here", "/value/1024/here"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"{var:3}", "val"},
+		{"{?undef}", ""},
+		{"/v1/disk/resources{?path,fields}", "/v1/disk/resources?path=%2Ffoo%2Fbar"},
+	}
+	for _, test := range tests {
+		link := &yadisk.Link{Href: test.template, Templated: true}
+		got, err := link.Expand(values)
+		if err != nil {
+			t.Errorf("Expand(%q) returned error %v", test.template, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Expand(%q) = %v, want %v", test.template, got, test.want)
+		}
+	}
+}
+
+func TestLink_Expand_not_templated(t *testing.T) {
+	link := &yadisk.Link{Href: "https://example.com/{var}"}
+	got, _ := link.Expand(map[string]string{"var": "value"})
+	if want := link.Href; got != want {
+		t.Errorf("Expand() = %v, want %v", got, want)
+	}
+}
+
+func TestLink_Expand_invalid_template(t *testing.T) {
+	for _, template := range []string{"{var", "{}", "{var:x}"} {
+		link := &yadisk.Link{Href: template, Templated: true}
+		if _, err := link.Expand(nil); err == nil {
+			t.Errorf("Expand(%q) should return error", template)
+		}
+	}
+}
+
+func TestClient_Follow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/operations/123", func(w http.ResponseWriter, r *http.Request) {
+		if m := "GET"; m != r.Method {
+			t.Errorf("Request method = %v, want %v", r.Method, m)
+		}
+		if got, want := r.Header.Get("Authorization"), "OAuth ACCESS_TOKEN"; got != want {
+			t.Errorf("Authorization header is %v, want %v", got, want)
+		}
+		fmt.Fprint(w, `{"status": "success"}`)
+	})
+
+	link := &yadisk.Link{
+		Href:      server.URL + "/v1/disk/operations/123{?fields}",
+		Method:    "GET",
+		Templated: true,
+	}
+	var v struct {
+		Status string `json:"status"`
+	}
+	response, err := client.Follow(context.Background(), link, &v)
+
+	if err != nil {
+		t.Fatalf("Follow returned error %v, %+v", err, response)
+	}
+	if got, want := v.Status, "success"; got != want {
+		t.Errorf("Follow decoded status %v, want %v", got, want)
+	}
+}
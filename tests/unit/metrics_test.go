@@ -0,0 +1,137 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/v1/disk/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "too many requests", 429)
+			return
+		}
+		w.Write([]byte(`{"total_space": 1}`))
+	})
+
+	collector := metrics.NewCollector("test")
+	client.Observer = collector
+	client.MaxRetries = 1
+
+	_, response, err := client.Disk.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Disk.Get returned error %v, %+v", err, response)
+	}
+
+	want := `
+# HELP test_yadisk_requests_total Number of finished Yandex.Disk API requests by operation and status.
+# TYPE test_yadisk_requests_total counter
+test_yadisk_requests_total{operation="disk.get",status="200"} 1
+test_yadisk_requests_total{operation="disk.get",status="429"} 1
+# HELP test_yadisk_requests_in_flight Number of Yandex.Disk API requests being currently sent.
+# TYPE test_yadisk_requests_in_flight gauge
+test_yadisk_requests_in_flight 0
+# HELP test_yadisk_retries_total Number of retried Yandex.Disk API requests by operation.
+# TYPE test_yadisk_retries_total counter
+test_yadisk_retries_total{operation="disk.get"} 1
+# HELP test_yadisk_throttled_total Number of Yandex.Disk API responses with 429 Too Many Requests by operation.
+# TYPE test_yadisk_throttled_total counter
+test_yadisk_throttled_total{operation="disk.get"} 1
+`
+	err = testutil.CollectAndCompare(
+		collector,
+		strings.NewReader(want),
+		"test_yadisk_requests_total",
+		"test_yadisk_requests_in_flight",
+		"test_yadisk_retries_total",
+		"test_yadisk_throttled_total",
+	)
+	if err != nil {
+		t.Errorf("Collected metrics mismatch: %v", err)
+	}
+}
+
+func TestCollector_duration_and_bytes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "0123456789")
+	})
+
+	collector := metrics.NewCollector("test")
+	client.Observer = collector
+
+	// The body of unknown length is sent chunked.
+	body := io.MultiReader(strings.NewReader("hello, "), strings.NewReader("world"))
+	req, _ := client.NewRequest("PUT", server.URL+"/upload", body)
+	if req.ContentLength > 0 {
+		t.Fatalf("Request ContentLength = %d, want unknown", req.ContentLength)
+	}
+	if _, err := client.Do(req, nil); err != nil {
+		t.Fatalf("Do returned error %v", err)
+	}
+	req, _ = client.NewRequest("GET", server.URL+"/download", nil)
+	if _, err := client.Do(req, new(bytes.Buffer)); err != nil {
+		t.Fatalf("Do returned error %v", err)
+	}
+
+	want := `
+# HELP test_yadisk_uploaded_bytes_total Number of bytes sent to Yandex.Disk.
+# TYPE test_yadisk_uploaded_bytes_total counter
+test_yadisk_uploaded_bytes_total 12
+# HELP test_yadisk_downloaded_bytes_total Number of bytes received from Yandex.Disk.
+# TYPE test_yadisk_downloaded_bytes_total counter
+test_yadisk_downloaded_bytes_total 10
+`
+	err := testutil.CollectAndCompare(
+		collector,
+		strings.NewReader(want),
+		"test_yadisk_uploaded_bytes_total",
+		"test_yadisk_downloaded_bytes_total",
+	)
+	if err != nil {
+		t.Errorf("Collected metrics mismatch: %v", err)
+	}
+
+	// Every request is observed in the histogram by its status.
+	if got, want := testutil.CollectAndCount(collector, "test_yadisk_request_duration_seconds"), 2; got != want {
+		t.Errorf("Collected %d duration series, want %d", got, want)
+	}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather returned error %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "test_yadisk_request_duration_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			if got := m.GetHistogram().GetSampleCount(); got != 1 {
+				t.Errorf("Duration sample count = %d, want 1", got)
+			}
+		}
+	}
+}
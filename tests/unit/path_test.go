@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want yadisk.Path
+		ns   yadisk.Namespace
+	}{
+		{"/photos/2020", "/photos/2020", yadisk.NamespaceDisk},
+		{"photos//2020/", "/photos/2020", yadisk.NamespaceDisk},
+		{"disk:/photos/../docs/./a.txt", "disk:/docs/a.txt", yadisk.NamespaceDisk},
+		{"app:/../cache", "app:/cache", yadisk.NamespaceApp},
+		{"trash:/", "trash:/", yadisk.NamespaceTrash},
+		{"/dir/ratio 1:2", "/dir/ratio 1:2", yadisk.NamespaceDisk},
+		{"", "/", yadisk.NamespaceDisk},
+	}
+	for _, tt := range tests {
+		got, err := yadisk.ParsePath(tt.in)
+		if err != nil {
+			t.Errorf("ParsePath(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if ns := got.Namespace(); ns != tt.ns {
+			t.Errorf("ParsePath(%q).Namespace() = %q, want %q", tt.in, ns, tt.ns)
+		}
+	}
+}
+
+func TestPath_Validate(t *testing.T) {
+	name := strings.Repeat("я", 255)
+	for _, p := range []yadisk.Path{
+		"/a\x00b",
+		"/a\nb",
+		"/a\x7fb",
+		"/\xff",
+		yadisk.Path("/photos/" + name + "я"),
+		yadisk.Path(strings.Repeat("/"+name, 130)),
+	} {
+		if err := p.Validate(); !errors.Is(err, yadisk.ErrInvalidPath) {
+			t.Errorf("Validate(%.20q) returned %v, want %v", p, err, yadisk.ErrInvalidPath)
+		}
+		if _, err := yadisk.ParsePath(string(p)); !errors.Is(err, yadisk.ErrInvalidPath) {
+			t.Errorf("ParsePath(%.20q) returned %v, want %v", p, err, yadisk.ErrInvalidPath)
+		}
+	}
+	if err := yadisk.Path("/photos/" + name).Validate(); err != nil {
+		t.Errorf("Validate of 255 character name returned error: %v", err)
+	}
+}
+
+func TestPath_Equal(t *testing.T) {
+	tests := []struct {
+		p, q yadisk.Path
+		want bool
+	}{
+		{"/photos", "disk:/photos/", true},
+		{"disk:/photos/2020/..", "/photos", true},
+		{"/", "disk:/", true},
+		{"/photos", "app:/photos", false},
+		{"/photos", "/Photos", false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Equal(tt.q); got != tt.want {
+			t.Errorf("Path(%q).Equal(%q) = %v, want %v", tt.p, tt.q, got, tt.want)
+		}
+	}
+	if got, want := yadisk.Path("/photos/").Canonical(), yadisk.Path("disk:/photos"); got != want {
+		t.Errorf("Canonical() = %q, want %q", got, want)
+	}
+}
+
+func TestPath_Join(t *testing.T) {
+	tests := []struct {
+		p    yadisk.Path
+		elem []string
+		want yadisk.Path
+	}{
+		{"/photos", []string{"2020", "a.jpg"}, "/photos/2020/a.jpg"},
+		{"disk:/photos/", []string{"/2020/"}, "disk:/photos/2020"},
+		{"app:/", []string{"cache"}, "app:/cache"},
+		{"/photos", []string{"../docs"}, "/docs"},
+		{"/", []string{"..", ".."}, "/"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Join(tt.elem...); got != tt.want {
+			t.Errorf("Path(%q).Join(%q) = %q, want %q", tt.p, tt.elem, got, tt.want)
+		}
+	}
+}
+
+func TestPath_DirBase(t *testing.T) {
+	tests := []struct {
+		p    yadisk.Path
+		dir  yadisk.Path
+		base string
+		root bool
+	}{
+		{"disk:/photos/2020/a.jpg", "disk:/photos/2020", "a.jpg", false},
+		{"/photos/", "/", "photos", false},
+		{"app:/", "app:/", "/", true},
+		{"", "/", "/", true},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Dir(); got != tt.dir {
+			t.Errorf("Path(%q).Dir() = %q, want %q", tt.p, got, tt.dir)
+		}
+		if got := tt.p.Base(); got != tt.base {
+			t.Errorf("Path(%q).Base() = %q, want %q", tt.p, got, tt.base)
+		}
+		if got := tt.p.IsRoot(); got != tt.root {
+			t.Errorf("Path(%q).IsRoot() = %v, want %v", tt.p, got, tt.root)
+		}
+	}
+}
+
+func TestPath_HasPrefix(t *testing.T) {
+	tests := []struct {
+		p, dir yadisk.Path
+		want   bool
+	}{
+		{"disk:/photos/2020", "/photos", true},
+		{"/photos", "disk:/photos/", true},
+		{"/photos", "/", true},
+		{"/photos2", "/photos", false},
+		{"/photo", "/photos", false},
+		{"app:/photos", "/photos", false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.HasPrefix(tt.dir); got != tt.want {
+			t.Errorf("Path(%q).HasPrefix(%q) = %v, want %v", tt.p, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestPath_TrashID(t *testing.T) {
+	tests := []struct {
+		p      yadisk.Path
+		id     string
+		wantOK bool
+	}{
+		{"trash:/foo_1408546879", "1408546879", true},
+		{"trash:/my_report.doc_1a2b3c/inner.txt", "1a2b3c", true},
+		{"trash:/", "", false},
+		{"trash:/foo", "", false},
+		{"trash:/foo_", "", false},
+		{"disk:/foo_1408546879", "", false},
+	}
+	for _, tt := range tests {
+		id, ok := tt.p.TrashID()
+		if id != tt.id || ok != tt.wantOK {
+			t.Errorf("Path(%q).TrashID() = %q, %v, want %q, %v", tt.p, id, ok, tt.id, tt.wantOK)
+		}
+	}
+}
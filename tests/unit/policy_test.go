@@ -0,0 +1,146 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestClient_WithPolicy_ReadOnly(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := 0
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"name": "a.txt", "path": "disk:/a.txt", "type": "file"}`)
+	})
+
+	ro := client.WithPolicy(yadisk.Policy{ReadOnly: true})
+	ctx := context.Background()
+	if _, _, err := ro.Resources.Get(ctx, "/a.txt", nil); err != nil {
+		t.Errorf("Resources.Get returned error: %v", err)
+	}
+
+	writes := map[string]func() error{
+		"Mkdir": func() error {
+			_, _, err := ro.Resources.Mkdir(ctx, "/dir")
+			return err
+		},
+		"Delete": func() error {
+			_, err := ro.Resources.Delete(ctx, "/a.txt", nil)
+			return err
+		},
+		"Move": func() error {
+			_, _, err := ro.Resources.Move(ctx, "/a.txt", "/b.txt", nil)
+			return err
+		},
+		"Upload": func() error {
+			_, err := ro.Resources.Upload(ctx, "/b.txt", bytes.NewReader([]byte("b")), nil)
+			return err
+		},
+		"NewRequest": func() error {
+			_, err := ro.NewRequest("PUT", "https://uploader.example.com/upload", nil)
+			return err
+		},
+	}
+	for name, write := range writes {
+		err := write()
+		var policyErr *yadisk.PolicyError
+		if !errors.Is(err, yadisk.ErrPolicyViolation) || !errors.As(err, &policyErr) {
+			t.Errorf("%s returned %v, want %v", name, err, yadisk.ErrPolicyViolation)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Sent %d requests, want 1", requests)
+	}
+}
+
+func TestClient_WithPolicy_Allow(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+
+	limited := client.WithPolicy(yadisk.Policy{Allow: []yadisk.Path{"/tenants/a", "app:/"}})
+	ctx := context.Background()
+	for _, p := range []yadisk.Path{"/tenants/a", "disk:/tenants/a/", "/tenants/a/photos", "app:/cache"} {
+		if _, _, err := limited.Resources.Get(ctx, p, nil); err != nil {
+			t.Errorf("Resources.Get(%q) returned error: %v", p, err)
+		}
+	}
+	for _, p := range []yadisk.Path{"/tenants", "/tenants/ab", "/tenants/a/../b", "trash:/tenants/a"} {
+		_, _, err := limited.Resources.Get(ctx, p, nil)
+		var policyErr *yadisk.PolicyError
+		if !errors.As(err, &policyErr) || policyErr.Path != p {
+			t.Errorf("Resources.Get(%q) returned %v, want *PolicyError for the path", p, err)
+		}
+	}
+	if _, _, err := limited.Resources.Copy(ctx, "/tenants/b/x", "/tenants/a/x", nil); !errors.Is(err, yadisk.ErrPolicyViolation) {
+		t.Errorf("Resources.Copy from a forbidden path returned %v, want %v", err, yadisk.ErrPolicyViolation)
+	}
+	if _, _, err := limited.Resources.GetTrash(ctx, "", nil); !errors.Is(err, yadisk.ErrPolicyViolation) {
+		t.Errorf("Resources.GetTrash of the whole Trash returned %v, want %v", err, yadisk.ErrPolicyViolation)
+	}
+	for _, endpoint := range []string{"disk/resources/files", "disk/resources/last-uploaded", "disk/resources/public"} {
+		if _, err := limited.NewRequest("GET", endpoint, nil); !errors.Is(err, yadisk.ErrPolicyViolation) {
+			t.Errorf("NewRequest for %s returned %v, want %v", endpoint, err, yadisk.ErrPolicyViolation)
+		}
+	}
+	if _, _, err := limited.Disk.Get(ctx); err != nil {
+		t.Errorf("Disk.Get returned error: %v", err)
+	}
+
+	// The scoped client paths are checked after the rewriting.
+	app := limited.AppFolder()
+	if _, _, err := app.Resources.Get(ctx, "/cache", nil); err != nil {
+		t.Errorf("Scoped Resources.Get returned error: %v", err)
+	}
+}
+
+func TestClient_WithPolicy_nested(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+
+	limited := client.WithPolicy(yadisk.Policy{ReadOnly: true, Allow: []yadisk.Path{"/tenants/a"}})
+	ctx := context.Background()
+
+	// A policy added to a restricted client can't widen the access.
+	for _, c := range []*yadisk.Client{
+		limited.WithPolicy(yadisk.Policy{}),
+		limited.WithPolicy(yadisk.Policy{Allow: []yadisk.Path{"/"}}),
+	} {
+		if _, _, err := c.Resources.Get(ctx, "/tenants/b", nil); !errors.Is(err, yadisk.ErrPolicyViolation) {
+			t.Errorf("Resources.Get of a forbidden path returned %v, want %v", err, yadisk.ErrPolicyViolation)
+		}
+		if _, _, err := c.Resources.Mkdir(ctx, "/tenants/a/dir"); !errors.Is(err, yadisk.ErrPolicyViolation) {
+			t.Errorf("Resources.Mkdir returned %v, want %v", err, yadisk.ErrPolicyViolation)
+		}
+		if _, _, err := c.Resources.Get(ctx, "/tenants/a", nil); err != nil {
+			t.Errorf("Resources.Get of an allowed path returned error: %v", err)
+		}
+	}
+
+	// It can narrow the access.
+	narrowed := limited.WithPolicy(yadisk.Policy{Allow: []yadisk.Path{"/tenants/a/photos", "/tenants/b"}})
+	if _, _, err := narrowed.Resources.Get(ctx, "/tenants/a/docs", nil); !errors.Is(err, yadisk.ErrPolicyViolation) {
+		t.Errorf("Resources.Get of a path outside the new policy returned %v, want %v", err, yadisk.ErrPolicyViolation)
+	}
+	if _, _, err := narrowed.Resources.Get(ctx, "/tenants/b", nil); !errors.Is(err, yadisk.ErrPolicyViolation) {
+		t.Errorf("Resources.Get of a path outside the old policy returned %v, want %v", err, yadisk.ErrPolicyViolation)
+	}
+	if _, _, err := narrowed.Resources.Get(ctx, "/tenants/a/photos/2020", nil); err != nil {
+		t.Errorf("Resources.Get of a path allowed by both returned error: %v", err)
+	}
+}
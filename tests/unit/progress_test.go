@@ -0,0 +1,125 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestProgressChan(t *testing.T) {
+	ch := make(chan yadisk.Progress)
+	progress := yadisk.ProgressChan(ch)
+
+	// Intermediate progress is dropped if nobody is receiving,
+	// even when all the data has been transferred.
+	progress(yadisk.Progress{Done: 1, Total: 2})
+	progress(yadisk.Progress{Done: 2, Total: 2})
+
+	// Final progress is always delivered, even of unknown total.
+	go progress(yadisk.Progress{Done: 2, Total: -1, Final: true})
+	select {
+	case p := <-ch:
+		if !p.Final || p.Done != 2 {
+			t.Errorf("Received progress %+v, want the final one", p)
+		}
+	case <-time.After(time.Second):
+		t.Error("Final progress wasn't delivered")
+	}
+}
+
+func TestResources_Upload_progress(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+
+	var progress []yadisk.Progress
+	opt := &yadisk.UploadOptions{
+		Progress: func(p yadisk.Progress) {
+			progress = append(progress, p)
+		},
+		ProgressInterval: time.Hour,
+	}
+	response, err := client.Resources.Upload(
+		context.Background(),
+		"/foo.txt",
+		strings.NewReader("hello"),
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.Upload returned error %v, %+v", err, response)
+	}
+	if got, want := string(uploader.data[1]), "hello"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+
+	// Only the final progress is reported within the interval.
+	if len(progress) != 1 {
+		t.Fatalf("Progress reported %v times, want 1", len(progress))
+	}
+	p := progress[0]
+	if p.Done != 5 || p.Total != 5 || !p.Final {
+		t.Errorf("Progress is %+v, want the final one with Done and Total 5", p)
+	}
+	if p.ETA != 0 {
+		t.Errorf("Progress ETA is %v, want 0", p.ETA)
+	}
+}
+
+func TestResources_Download_progress(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("0123456789"))
+	defer downloader.close()
+
+	var last yadisk.Progress
+	opt := &yadisk.DownloadOptions{
+		Progress: func(p yadisk.Progress) {
+			last = p
+		},
+	}
+	_, err := client.Resources.Download(context.Background(), "/foo.bin", new(bytes.Buffer), opt)
+
+	if err != nil {
+		t.Fatalf("Resources.Download returned error %v", err)
+	}
+	if last.Done != 10 || last.Total != 10 {
+		t.Errorf("Last progress is %+v, want Done and Total 10", last)
+	}
+	if last.AverageRate <= 0 {
+		t.Errorf("Last progress AverageRate is %v, want more than zero", last.AverageRate)
+	}
+}
+
+func TestResources_DownloadTo_progress(t *testing.T) {
+	setup()
+	defer teardown()
+
+	downloader := newFakeDownloader([]byte("0123456789"))
+	downloader.failures = 1
+	defer downloader.close()
+
+	var last yadisk.Progress
+	opt := &yadisk.DownloadOptions{
+		ChunkSize: 3,
+		Progress: func(p yadisk.Progress) {
+			last = p
+		},
+	}
+	dst := &memFile{}
+	_, err := client.Resources.DownloadTo(context.Background(), "/foo.bin", dst, opt)
+
+	if err != nil {
+		t.Fatalf("Resources.DownloadTo returned error %v", err)
+	}
+	if last.Done != 10 || last.Total != 10 {
+		t.Errorf("Last progress is %+v, want Done and Total 10", last)
+	}
+}
@@ -2,6 +2,7 @@ package unit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
@@ -14,10 +15,13 @@ func TestResources_Get_file(t *testing.T) {
 	setup()
 	defer teardown()
 
-	mux.HandleFunc("/v1/resources/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
 		if m := "GET"; m != r.Method {
 			t.Errorf("Request method = %v, want %v", r.Method, m)
 		}
+		if got, want := r.URL.Query().Get("path"), "/Горы.jpg"; got != want {
+			t.Errorf("Request path parameter = %v, want %v", got, want)
+		}
 		fmt.Fprint(
 			w,
 			`
@@ -40,7 +44,7 @@ func TestResources_Get_file(t *testing.T) {
 		)
 	})
 
-	resource, response, err := client.Resources.Get(context.Background(), "/", nil)
+	resource, response, err := client.Resources.Get(context.Background(), "/Горы.jpg", nil)
 
 	if err != nil {
 		t.Errorf("Resources.Get returned error %v, %+v", err, response)
@@ -57,13 +61,13 @@ func TestResources_Get_file(t *testing.T) {
 		t.Errorf("Returned resource Name is %v, want %v", got, want)
 	}
 	wantCreated := time.Date(
-		2017,          // year
-		time.February, // month
-		26,            // day
-		9,             // hour
-		4,             // min
-		44,            // sec
-		0,             // nsec
+		2017,                       // year
+		time.February,              // month
+		26,                         // day
+		9,                          // hour
+		4,                          // min
+		44,                         // sec
+		0,                          // nsec
 		time.FixedZone("+0000", 0), // loc
 	)
 	if !resource.Created.Equal(wantCreated) {
@@ -79,19 +83,19 @@ func TestResources_Get_file(t *testing.T) {
 		t.Errorf("Returned resource OriginPath is %v, want nil", resource.OriginPath)
 	}
 	wantModified := time.Date(
-		2017,          // year
-		time.February, // month
-		26,            // day
-		9,             // hour
-		24,            // min
-		44,            // sec
-		0,             // nsec
+		2017,                       // year
+		time.February,              // month
+		26,                         // day
+		9,                          // hour
+		24,                         // min
+		44,                         // sec
+		0,                          // nsec
 		time.FixedZone("+0000", 0), // loc
 	)
 	if !resource.Modified.Equal(wantModified) {
 		t.Errorf("Returned resource Modified is %+v, want %+v", resource.Modified, wantModified)
 	}
-	if got, want := resource.Path, "disk:/Горы.jpg"; got != want {
+	if got, want := resource.Path, yadisk.Path("disk:/Горы.jpg"); got != want {
 		t.Errorf("Returned resource Path is %v, want %v", got, want)
 	}
 	if got, want := resource.MD5, "1392851f0668017168ee4b5a59d66e7b"; got != want {
@@ -120,6 +124,31 @@ func TestResources_Get_file(t *testing.T) {
 	}
 }
 
+func TestResources_Get_options(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		want := "fields=name%2C_embedded.items.path&limit=5&offset=10&path=%2Ffoo&sort=-name"
+		if got := r.URL.RawQuery; got != want {
+			t.Errorf("Request query = %v, want %v", got, want)
+		}
+		fmt.Fprint(w, `{"name": "foo", "type": "dir"}`)
+	})
+
+	opt := &yadisk.ResourcesOptions{
+		Sort:   "-name",
+		Limit:  5,
+		Offset: 10,
+		Fields: []string{"name", "_embedded.items.path"},
+	}
+	_, response, err := client.Resources.Get(context.Background(), "/foo", opt)
+
+	if err != nil {
+		t.Errorf("Resources.Get returned error %v, %+v", err, response)
+	}
+}
+
 func _TestResources_Get_with_http_error(t *testing.T) {
 	setup()
 	defer teardown()
@@ -139,3 +168,76 @@ func _TestResources_Get_with_http_error(t *testing.T) {
 		t.Errorf("Disk.Get should return disk as nil if HTTP error occured")
 	}
 }
+
+func TestResources_Mkdir(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		if m := "PUT"; m != r.Method {
+			t.Errorf("Request method = %v, want %v", r.Method, m)
+		}
+		if got, want := r.URL.Query().Get("path"), "/foo"; got != want {
+			t.Errorf("Request path query = %v, want %v", got, want)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"href": "https://cloud-api.yandex.net/v1/disk/resources?path=disk%3A%2Ffoo", "method": "GET"}`)
+	})
+
+	link, response, err := client.Resources.Mkdir(context.Background(), "/foo")
+
+	if err != nil {
+		t.Fatalf("Resources.Mkdir returned error %v, %+v", err, response)
+	}
+	if got, want := link.Method, "GET"; got != want {
+		t.Errorf("Link method is %v, want %v", got, want)
+	}
+}
+
+func TestResources_MkdirAll(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/foo/file.txt", "", time.Time{})
+
+	if err := client.Resources.MkdirAll(context.Background(), "disk:/foo/bar/baz"); err != nil {
+		t.Fatalf("Resources.MkdirAll returned error %v", err)
+	}
+	if !disk.dir("/foo/bar/baz") {
+		t.Errorf("Folder is not created")
+	}
+
+	// A file on the way is an error.
+	if err := client.Resources.MkdirAll(context.Background(), "/foo/file.txt/bar"); err == nil {
+		t.Errorf("Resources.MkdirAll returned no error for a file on the path")
+	}
+}
+
+func TestResources_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	for i := 0; i < 150; i++ {
+		disk.addFile(fmt.Sprintf("/dir/%03d.txt", i), "data", time.Time{})
+	}
+
+	ctx := context.Background()
+	items, err := client.Resources.List(ctx, "/dir", &yadisk.ListOptions{Fields: []string{"name"}})
+	if err != nil {
+		t.Fatalf("Resources.List returned error %v", err)
+	}
+	if got, want := len(items), 150; got != want {
+		t.Errorf("Resources.List returned %v items, want %v", got, want)
+	}
+	if got, want := items[149].Name, "149.txt"; got != want {
+		t.Errorf("Last item name is %v, want %v", got, want)
+	}
+
+	if _, err := client.Resources.List(ctx, "/dir/000.txt", nil); !errors.Is(err, yadisk.ErrNotFolder) {
+		t.Errorf("Resources.List of a file returned %v, want %v", err, yadisk.ErrNotFolder)
+	}
+}
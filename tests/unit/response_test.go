@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestResponse_metadata(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Yandex-Cloud-Request-ID", "rest-123")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.Header().Set("X-RateLimit-Reset", "1488099884")
+		fmt.Fprint(w, `{"A":"a"}`)
+	})
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	response, err := client.Do(req, nil)
+
+	if err != nil {
+		t.Fatalf("Do returned error %v", err)
+	}
+	if got, want := response.StatusCode, 200; got != want {
+		t.Errorf("Response StatusCode is %v, want %v", got, want)
+	}
+	if got, want := response.RequestID, "rest-123"; got != want {
+		t.Errorf("Response RequestID is %v, want %v", got, want)
+	}
+	wantRate := yadisk.Rate{
+		Limit:     100,
+		Remaining: 42,
+		Reset:     time.Unix(1488099884, 0),
+	}
+	if !reflect.DeepEqual(response.Rate, wantRate) {
+		t.Errorf("Response Rate is %+v, want %+v", response.Rate, wantRate)
+	}
+	if response.Operation != nil {
+		t.Errorf("Response Operation is %v, want nil", response.Operation)
+	}
+	if got, want := response.Retries, 0; got != want {
+		t.Errorf("Response Retries is %v, want %v", got, want)
+	}
+	if response.Latency <= 0 {
+		t.Errorf("Response Latency is %v, want more than zero", response.Latency)
+	}
+}
+
+func TestResponse_operation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(
+			w,
+			`
+            {
+                "href": "https://cloud-api.yandex.net/v1/disk/operations/33ca7d03ab21ct41b4a40182e78d828a3f8b72cdb5f4c0e94cc4b1449a63a2fe",
+                "method": "GET",
+                "templated": false
+            }
+            `,
+		)
+	})
+
+	req, _ := client.NewRequest("GET", "/", nil)
+	link := new(yadisk.Link)
+	response, err := client.Do(req, link)
+
+	if err != nil {
+		t.Fatalf("Do returned error %v", err)
+	}
+	want := &yadisk.Link{
+		Href:   "https://cloud-api.yandex.net/v1/disk/operations/33ca7d03ab21ct41b4a40182e78d828a3f8b72cdb5f4c0e94cc4b1449a63a2fe",
+		Method: "GET",
+	}
+	if !reflect.DeepEqual(response.Operation, want) {
+		t.Errorf("Response Operation is %+v, want %+v", response.Operation, want)
+	}
+	if !reflect.DeepEqual(link, want) {
+		t.Errorf("Response body = %+v, want %+v", link, want)
+	}
+}
+
+func TestResponse_retries(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		if calls == 1 {
+			http.Error(w, "too many requests", 429)
+		}
+	})
+
+	client.MaxRetries = 3
+	req, _ := client.NewRequest("GET", "/", nil)
+	response, err := client.Do(req, nil)
+
+	if err != nil {
+		t.Fatalf("Do returned error %v", err)
+	}
+	if got, want := response.Retries, 1; got != want {
+		t.Errorf("Response Retries is %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+func TestClient_AppFolder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var paths []string
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Query().Get("path"))
+		fmt.Fprint(w, `{"name": "cache", "path": "app:/cache", "type": "dir", "_embedded": {
+			"path": "app:/cache", "total": 1, "items": [
+				{"name": "a.txt", "path": "app:/cache/a.txt", "type": "file"}
+			]}}`)
+	})
+	mux.HandleFunc("/v1/disk/resources/move", func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Query().Get("from"), r.URL.Query().Get("path"))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"href": "https://cloud-api.yandex.net/v1/disk/resources?path=app%3A%2Fnew", "method": "GET"}`)
+	})
+
+	app := client.AppFolder()
+	ctx := context.Background()
+	for _, p := range []yadisk.Path{"/cache", "cache/", "app:/cache", "/tmp/../cache"} {
+		resource, _, err := app.Resources.Get(ctx, p, nil)
+		if err != nil {
+			t.Fatalf("Resources.Get(%q) returned error: %v", p, err)
+		}
+		if resource.Path != "/cache" || resource.Embedded.Path != "/cache" || resource.Embedded.Items[0].Path != "/cache/a.txt" {
+			t.Errorf("Resources.Get(%q) returned paths %q, %q, %q, want them relative to the app folder",
+				p, resource.Path, resource.Embedded.Path, resource.Embedded.Items[0].Path)
+		}
+	}
+	if _, _, err := app.Resources.Move(ctx, "/old", "/new", nil); err != nil {
+		t.Fatalf("Resources.Move returned error: %v", err)
+	}
+	want := []string{"app:/cache", "app:/cache", "app:/cache", "app:/cache", "app:/old", "app:/new"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Requested paths %q, want %q", paths, want)
+	}
+
+	// The original client isn't scoped.
+	resource, _, err := client.Resources.Get(ctx, "/cache", nil)
+	if err != nil {
+		t.Fatalf("Resources.Get returned error: %v", err)
+	}
+	if resource.Path != "app:/cache" || paths[len(paths)-1] != "/cache" {
+		t.Errorf("Unscoped client requested %q and returned %q", paths[len(paths)-1], resource.Path)
+	}
+}
+
+func TestClient_AppFolder_applications_paths(t *testing.T) {
+	setup()
+	defer teardown()
+
+	diskRequests := 0
+	mux.HandleFunc("/v1/disk/", func(w http.ResponseWriter, r *http.Request) {
+		diskRequests++
+		fmt.Fprint(w, `{"system_folders": {"applications": "disk:/Приложения"}}`)
+	})
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name": "MyApp", "path": "disk:/Приложения/MyApp", "type": "dir", "_embedded": {
+			"total": 1, "items": [
+				{"name": "a.txt", "path": "disk:/Приложения/MyApp/a.txt", "type": "file"}
+			]}}`)
+	})
+
+	app := client.AppFolder()
+	for i := 0; i < 2; i++ {
+		resource, _, err := app.Resources.Get(context.Background(), "/", nil)
+		if err != nil {
+			t.Fatalf("Resources.Get returned error: %v", err)
+		}
+		if resource.Path != "/" || resource.Embedded.Items[0].Path != "/a.txt" {
+			t.Errorf("Resources.Get returned paths %q, %q, want %q, %q",
+				resource.Path, resource.Embedded.Items[0].Path, "/", "/a.txt")
+		}
+	}
+	if diskRequests != 1 {
+		t.Errorf("System folders requested %d times, want 1", diskRequests)
+	}
+}
+
+func TestClient_AppFolder_out_of_scope(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request %s", r.URL)
+	})
+
+	app := client.AppFolder()
+	ctx := context.Background()
+	for _, p := range []yadisk.Path{"disk:/secret", "trash:/foo_1408546879", "/../secret", "app:/cache/../../secret"} {
+		if _, _, err := app.Resources.Get(ctx, p, nil); !errors.Is(err, yadisk.ErrOutOfScope) {
+			t.Errorf("Resources.Get(%q) returned %v, want %v", p, err, yadisk.ErrOutOfScope)
+		}
+	}
+	if _, _, err := app.Resources.Copy(ctx, "disk:/secret", "/copy", nil); !errors.Is(err, yadisk.ErrOutOfScope) {
+		t.Errorf("Resources.Copy returned %v, want %v", err, yadisk.ErrOutOfScope)
+	}
+	if _, _, err := app.Resources.GetTrash(ctx, "/", nil); !errors.Is(err, yadisk.ErrOutOfScope) {
+		t.Errorf("Resources.GetTrash returned %v, want %v", err, yadisk.ErrOutOfScope)
+	}
+}
@@ -0,0 +1,253 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+	"github.com/chibisov/go-yadisk/yadisk/transfer"
+)
+
+func TestQueue_Run(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/remote.txt", "remote", time.Time{})
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"local.txt": "local"})
+	journalPath := filepath.Join(dir, "journal.jsonl")
+
+	queue, err := transfer.Open(client, journalPath, nil)
+	if err != nil {
+		t.Fatalf("transfer.Open returned error %v", err)
+	}
+	uploadID, _ := queue.Upload(filepath.Join(dir, "local.txt"), "/local.txt")
+	downloadID, _ := queue.Download("/remote.txt", filepath.Join(dir, "remote.txt"))
+
+	if got, want := queue.Stats(), (transfer.Stats{Queued: 2}); got != want {
+		t.Errorf("Stats before Run are %+v, want %+v", got, want)
+	}
+	if err := queue.Run(context.Background()); err != nil {
+		t.Fatalf("Queue.Run returned error %v", err)
+	}
+	if got, want := queue.Stats(), (transfer.Stats{Done: 2}); got != want {
+		t.Errorf("Stats after Run are %+v, want %+v", got, want)
+	}
+	queue.Close()
+
+	if data, _ := disk.file("/local.txt"); data != "local" {
+		t.Errorf("Uploaded file contents are %q, want %q", data, "local")
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "remote.txt")); string(data) != "remote" {
+		t.Errorf("Downloaded file contents are %q, want %q", data, "remote")
+	}
+
+	// The state is restored from the journal.
+	queue, err = transfer.Open(client, journalPath, nil)
+	if err != nil {
+		t.Fatalf("transfer.Open returned error %v", err)
+	}
+	defer queue.Close()
+	for _, id := range []int64{uploadID, downloadID} {
+		item, err := queue.Get(id)
+		if err != nil || item.State != transfer.Done || item.Attempts != 1 {
+			t.Errorf("Restored transfer %v is %+v, %v, want done once", id, item, err)
+		}
+	}
+	if id, _ := queue.Upload("foo", "/foo"); id != 3 {
+		t.Errorf("New transfer ID is %v, want 3", id)
+	}
+}
+
+func TestQueue_Run_resume_upload(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveUploadedResource(t, uploader, false)
+	// The upload was interrupted by a restart after 4 bytes.
+	uploader.links = 1
+	uploader.data[1] = []byte("0123")
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"foo.txt": "0123456789"})
+	journalPath := filepath.Join(dir, "journal.jsonl")
+	item := transfer.Item{
+		ID:         1,
+		Kind:       transfer.Upload,
+		LocalPath:  filepath.Join(dir, "foo.txt"),
+		Path:       "/foo.txt",
+		State:      transfer.Running,
+		Attempts:   1,
+		UploadLink: &yadisk.Link{Href: uploader.server.URL + "/upload/1", Method: "PUT"},
+	}
+	data, _ := json.Marshal(item)
+	// The journal ends with a line cut off by the crash.
+	ioutil.WriteFile(journalPath, append(data, "\n{\"id\": 2, \"ki"...), 0644)
+
+	queue, err := transfer.Open(client, journalPath, nil)
+	if err != nil {
+		t.Fatalf("transfer.Open returned error %v", err)
+	}
+	defer queue.Close()
+	if got, _ := queue.Get(1); got.State != transfer.Queued {
+		t.Errorf("Interrupted transfer is %v, want queued", got.State)
+	}
+
+	if err := queue.Run(context.Background()); err != nil {
+		t.Fatalf("Queue.Run returned error %v", err)
+	}
+
+	if got, want := string(uploader.data[1]), "0123456789"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+	if got, want := strings.Join(uploader.ranges, ","), "bytes */10,bytes 4-9/10"; got != want {
+		t.Errorf("Content ranges are %v, want %v", got, want)
+	}
+	if got, _ := queue.Get(1); got.State != transfer.Done || got.UploadLink != nil {
+		t.Errorf("Transfer is %+v, want done without the link", got)
+	}
+}
+
+func TestQueue_Run_failure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+
+	dir := t.TempDir()
+	queue, err := transfer.Open(client, filepath.Join(dir, "journal.jsonl"), &transfer.Options{
+		MaxAttempts: 2,
+	})
+	if err != nil {
+		t.Fatalf("transfer.Open returned error %v", err)
+	}
+	defer queue.Close()
+
+	id, _ := queue.Download("/missing.txt", filepath.Join(dir, "missing.txt"))
+	if err := queue.Run(context.Background()); err != nil {
+		t.Fatalf("Queue.Run returned error %v", err)
+	}
+
+	item, _ := queue.Get(id)
+	if item.State != transfer.Failed || item.Attempts != 2 || item.Error == "" {
+		t.Errorf("Transfer is %+v, want failed after 2 attempts", item)
+	}
+
+	// The file appears and the transfer is retried.
+	disk.addFile("/missing.txt", "found", time.Time{})
+	if err := queue.Retry(id); err != nil {
+		t.Fatalf("Queue.Retry returned error %v", err)
+	}
+	if err := queue.Run(context.Background()); err != nil {
+		t.Fatalf("Queue.Run returned error %v", err)
+	}
+	if item, _ := queue.Get(id); item.State != transfer.Done {
+		t.Errorf("Retried transfer is %v, want done", item.State)
+	}
+	if err := queue.Retry(100); err != transfer.ErrNotFound {
+		t.Errorf("Queue.Retry of unknown transfer returned %v, want %v", err, transfer.ErrNotFound)
+	}
+}
+
+func TestQueue_Run_canceled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"foo.txt": "foo"})
+	queue, err := transfer.Open(client, filepath.Join(dir, "journal.jsonl"), nil)
+	if err != nil {
+		t.Fatalf("transfer.Open returned error %v", err)
+	}
+	defer queue.Close()
+	id, _ := queue.Upload(filepath.Join(dir, "foo.txt"), "/foo.txt")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := queue.Run(ctx); err != context.Canceled {
+		t.Errorf("Queue.Run returned error %v, want %v", err, context.Canceled)
+	}
+
+	if item, _ := queue.Get(id); item.State != transfer.Queued || item.Attempts != 0 {
+		t.Errorf("Canceled transfer is %+v, want queued", item)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "journal.jsonl")); err != nil {
+		t.Errorf("Journal is not written: %v", err)
+	}
+}
+
+func TestQueue_Run_verify_upload(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	serveUploadedResource(t, uploader, true)
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"foo.txt": "hello"})
+
+	queue, err := transfer.Open(client, filepath.Join(dir, "journal.jsonl"), nil)
+	if err != nil {
+		t.Fatalf("transfer.Open returned error %v", err)
+	}
+	defer queue.Close()
+	id, _ := queue.Upload(filepath.Join(dir, "foo.txt"), "/foo.txt")
+
+	if err := queue.Run(context.Background()); err != nil {
+		t.Fatalf("Queue.Run returned error %v", err)
+	}
+	if got, _ := queue.Get(id); got.State != transfer.Failed || !strings.Contains(got.Error, "checksum") {
+		t.Errorf("Transfer is %+v, want failed with the checksum mismatch", got)
+	}
+}
+
+func TestQueue_Run_compacts_journal(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.txt": "a", "b.txt": "b"})
+	journalPath := filepath.Join(dir, "journal.jsonl")
+
+	queue, err := transfer.Open(client, journalPath, nil)
+	if err != nil {
+		t.Fatalf("transfer.Open returned error %v", err)
+	}
+	defer queue.Close()
+	queue.Upload(filepath.Join(dir, "a.txt"), "/a.txt")
+	queue.Upload(filepath.Join(dir, "b.txt"), "/b.txt")
+
+	if err := queue.Run(context.Background()); err != nil {
+		t.Fatalf("Queue.Run returned error %v", err)
+	}
+	data, _ := ioutil.ReadFile(journalPath)
+	if got, want := strings.Count(string(data), "\n"), 2; got != want {
+		t.Errorf("Journal has %d lines after Run, want %d", got, want)
+	}
+
+	// The journal is appended to after the compaction.
+	queue.Upload(filepath.Join(dir, "a.txt"), "/c.txt")
+	data, _ = ioutil.ReadFile(journalPath)
+	if got, want := strings.Count(string(data), "\n"), 3; got != want {
+		t.Errorf("Journal has %d lines after Upload, want %d", got, want)
+	}
+}
@@ -1,9 +1,11 @@
 package unit
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 
 	"github.com/chibisov/go-yadisk/yadisk"
 )
@@ -37,3 +39,41 @@ func setup() {
 func teardown() {
 	server.Close()
 }
+
+// memFile is an in-memory file implementing io.WriterAt and io.ReaderAt.
+type memFile struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	return copy(f.data[off:], p), nil
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Bytes returns the file contents.
+func (f *memFile) Bytes() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]byte(nil), f.data...)
+}
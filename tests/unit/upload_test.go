@@ -0,0 +1,447 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// fakeUploader is a stand-in for the Yandex.Disk uploader.
+// It issues upload links and stores the uploaded files in memory.
+type fakeUploader struct {
+	mu sync.Mutex
+
+	// Uploaded data by the link number.
+	data map[int][]byte
+
+	// Number of issued links.
+	links int
+
+	// Links which respond with 410 Gone.
+	expired map[int]bool
+
+	// If positive, the connection is closed after reading
+	// that many bytes of the next chunk.
+	breakAfter int
+
+	// Received Content-Range headers.
+	ranges []string
+
+	// Data available for the instant upload by MD5 hash.
+	known map[string][]byte
+
+	// If set, the instant upload requests are answered with the status.
+	instantStatus int
+
+	// Separate server, since the OAuth token is sent to the API host only.
+	server *httptest.Server
+}
+
+// newFakeUploader starts the fake uploader server and registers
+// the upload link handler on mux. The uploader should be closed
+// after the test.
+func newFakeUploader() *fakeUploader {
+	u := &fakeUploader{
+		data:    make(map[int][]byte),
+		expired: make(map[int]bool),
+		known:   make(map[string][]byte),
+	}
+	u.server = httptest.NewServer(http.HandlerFunc(u.serveUpload))
+	mux.HandleFunc("/v1/disk/resources/upload", u.serveLink)
+	return u
+}
+
+// close shuts down the uploader server.
+func (u *fakeUploader) close() {
+	u.server.Close()
+}
+
+func (u *fakeUploader) serveLink(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	u.links++
+	n := u.links
+	u.mu.Unlock()
+
+	fmt.Fprintf(
+		w,
+		`{"href": "%s/upload/%d", "method": "PUT", "templated": false}`,
+		u.server.URL,
+		n,
+	)
+}
+
+func (u *fakeUploader) serveUpload(w http.ResponseWriter, r *http.Request) {
+	n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/upload/"))
+	if got := r.Header.Get("Authorization"); got != "" {
+		http.Error(w, "authorization must not be sent to the uploader", 400)
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.expired[n] {
+		http.Error(w, "link expired", http.StatusGone)
+		return
+	}
+
+	// Instant upload request.
+	if etag := r.Header.Get("Etag"); etag != "" {
+		if u.instantStatus != 0 {
+			http.Error(w, http.StatusText(u.instantStatus), u.instantStatus)
+			return
+		}
+		data, ok := u.known[etag]
+		if !ok {
+			http.Error(w, "unknown hash", http.StatusPreconditionFailed)
+			return
+		}
+		u.data[n] = data
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	contentRange := r.Header.Get("Content-Range")
+	u.ranges = append(u.ranges, contentRange)
+	data := u.data[n]
+
+	// Upload state request.
+	if strings.HasPrefix(contentRange, "bytes */") {
+		size, _ := strconv.Atoi(strings.TrimPrefix(contentRange, "bytes */"))
+		if len(data) == size {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if len(data) > 0 {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(data)-1))
+		}
+		w.WriteHeader(http.StatusPermanentRedirect)
+		return
+	}
+
+	size := int(r.ContentLength)
+	if contentRange != "" {
+		var start, end int
+		fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &size)
+		if start != len(data) {
+			http.Error(w, "unexpected range start", 400)
+			return
+		}
+	}
+
+	if u.breakAfter > 0 {
+		chunk := make([]byte, u.breakAfter)
+		io.ReadFull(r.Body, chunk)
+		u.data[n] = append(data, chunk...)
+		u.breakAfter = 0
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+		return
+	}
+
+	chunk, _ := io.ReadAll(r.Body)
+	u.data[n] = append(data, chunk...)
+	if len(u.data[n]) < size {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(u.data[n])-1))
+		w.WriteHeader(http.StatusPermanentRedirect)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func TestResources_GetUploadLink(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/disk/resources/upload", func(w http.ResponseWriter, r *http.Request) {
+		if m := "GET"; m != r.Method {
+			t.Errorf("Request method = %v, want %v", r.Method, m)
+		}
+		if got, want := r.URL.RawQuery, "overwrite=true&path=%2Ffoo.txt"; got != want {
+			t.Errorf("Request query = %v, want %v", got, want)
+		}
+		fmt.Fprint(
+			w,
+			`
+            {
+                "href": "https://uploader1d.dst.yandex.net:443/upload-target/123",
+                "method": "PUT",
+                "templated": false
+            }
+            `,
+		)
+	})
+
+	opt := &yadisk.UploadOptions{Overwrite: true}
+	link, response, err := client.Resources.GetUploadLink(context.Background(), "/foo.txt", opt)
+
+	if err != nil {
+		t.Fatalf("Resources.GetUploadLink returned error %v, %+v", err, response)
+	}
+	if got, want := link.Href, "https://uploader1d.dst.yandex.net:443/upload-target/123"; got != want {
+		t.Errorf("Returned link Href is %v, want %v", got, want)
+	}
+	if got, want := link.Method, "PUT"; got != want {
+		t.Errorf("Returned link Method is %v, want %v", got, want)
+	}
+}
+
+func TestResources_Upload(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+
+	response, err := client.Resources.Upload(
+		context.Background(),
+		"/foo.txt",
+		strings.NewReader("hello"),
+		nil,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.Upload returned error %v, %+v", err, response)
+	}
+	if got, want := string(uploader.data[1]), "hello"; got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+}
+
+func TestResources_UploadResumable_chunks(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	data := []byte("0123456789")
+
+	var progress []yadisk.Progress
+	opt := &yadisk.ResumableUploadOptions{
+		UploadOptions: yadisk.UploadOptions{
+			Progress: func(p yadisk.Progress) {
+				progress = append(progress, p)
+			},
+		},
+		ChunkSize: 4,
+	}
+	response, err := client.Resources.UploadResumable(
+		context.Background(),
+		"/foo.txt",
+		bytes.NewReader(data),
+		int64(len(data)),
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadResumable returned error %v, %+v", err, response)
+	}
+	if got, want := string(uploader.data[1]), string(data); got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+	wantRanges := "bytes 0-3/10,bytes 4-7/10,bytes 8-9/10"
+	if got := strings.Join(uploader.ranges, ","); got != wantRanges {
+		t.Errorf("Content-Range headers are %v, want %v", got, wantRanges)
+	}
+	last := progress[len(progress)-1]
+	if last.Done != 10 || last.Total != 10 {
+		t.Errorf("Last progress is %+v, want Done and Total 10", last)
+	}
+}
+
+func TestResources_UploadResumable_network_failure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	uploader.breakAfter = 3
+	data := []byte("0123456789")
+
+	src, size, _ := yadisk.SeekReaderAt(bytes.NewReader(data))
+	response, err := client.Resources.UploadResumable(
+		context.Background(),
+		"/foo.txt",
+		src,
+		size,
+		nil,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadResumable returned error %v, %+v", err, response)
+	}
+	if got, want := string(uploader.data[1]), string(data); got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+	wantRanges := ",bytes */10,bytes 3-9/10"
+	if got := strings.Join(uploader.ranges, ","); got != wantRanges {
+		t.Errorf("Content-Range headers are %v, want %v", got, wantRanges)
+	}
+}
+
+func TestResources_UploadResumable_expired_link(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	uploader.expired[1] = true
+	data := []byte("0123456789")
+
+	response, err := client.Resources.UploadResumable(
+		context.Background(),
+		"/foo.txt",
+		bytes.NewReader(data),
+		int64(len(data)),
+		nil,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadResumable returned error %v, %+v", err, response)
+	}
+	if got, want := uploader.links, 2; got != want {
+		t.Errorf("Upload links requested %v times, want %v", got, want)
+	}
+	if got, want := string(uploader.data[2]), string(data); got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+}
+
+func TestResources_UploadResumable_link_not_found(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// The uploader responds with 404 to the first link.
+	var mu sync.Mutex
+	links := 0
+	var uploaded []byte
+	uploader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/upload/1" {
+			http.NotFound(w, r)
+			return
+		}
+		uploaded, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer uploader.Close()
+	mux.HandleFunc("/v1/disk/resources/upload", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		links++
+		n := links
+		mu.Unlock()
+		fmt.Fprintf(w, `{"href": "%s/upload/%d", "method": "PUT"}`, uploader.URL, n)
+	})
+
+	data := []byte("0123456789")
+	_, err := client.Resources.UploadResumable(context.Background(), "/foo.txt", bytes.NewReader(data), int64(len(data)), nil)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadResumable returned error %v", err)
+	}
+	if links != 2 || string(uploaded) != string(data) {
+		t.Errorf("Requested %d links and uploaded %q, want 2 links and %q", links, uploaded, data)
+	}
+}
+
+func TestResources_UploadResumable_long_retry_after(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploads := 0
+	uploader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		w.Header().Set("Retry-After", "86400")
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+	}))
+	defer uploader.Close()
+	mux.HandleFunc("/v1/disk/resources/upload", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"href": "%s/upload/1", "method": "PUT"}`, uploader.URL)
+	})
+
+	data := []byte("0123456789")
+	start := time.Now()
+	_, err := client.Resources.UploadResumable(context.Background(), "/foo.txt", bytes.NewReader(data), int64(len(data)), nil)
+
+	if apiErr, ok := err.(*yadisk.APIError); !ok || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Resources.UploadResumable returned error %v, want the uploader error", err)
+	}
+	if uploads != 1 {
+		t.Errorf("Upload requests sent %v times, want 1", uploads)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Resources.UploadResumable waited %v", elapsed)
+	}
+}
+
+func TestResources_UploadResumable_missing_folder(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// The API responds with 404 to the upload into a missing folder.
+	links := 0
+	mux.HandleFunc("/v1/disk/resources/upload", func(w http.ResponseWriter, r *http.Request) {
+		links++
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": "DiskNotFoundError", "description": "Resource not found."}`)
+	})
+
+	data := []byte("0123456789")
+	_, err := client.Resources.UploadResumable(context.Background(), "/missing/foo.txt", bytes.NewReader(data), int64(len(data)), nil)
+
+	if apiErr, ok := err.(*yadisk.APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Resources.UploadResumable returned error %v, want the API error", err)
+	}
+	if links != 1 {
+		t.Errorf("Upload links requested %v times, want 1", links)
+	}
+}
+
+func TestResources_UploadResumable_saved_link(t *testing.T) {
+	setup()
+	defer teardown()
+
+	uploader := newFakeUploader()
+	defer uploader.close()
+	data := []byte("0123456789")
+	// The link of the upload interrupted by a restart.
+	uploader.links = 1
+	uploader.data[1] = data[:4]
+
+	var links []string
+	opt := &yadisk.ResumableUploadOptions{
+		Link:   &yadisk.Link{Href: uploader.server.URL + "/upload/1", Method: "PUT"},
+		OnLink: func(link *yadisk.Link) { links = append(links, link.Href) },
+	}
+	response, err := client.Resources.UploadResumable(
+		context.Background(),
+		"/foo.txt",
+		bytes.NewReader(data),
+		int64(len(data)),
+		opt,
+	)
+
+	if err != nil {
+		t.Fatalf("Resources.UploadResumable returned error %v, %+v", err, response)
+	}
+	if got, want := string(uploader.data[1]), string(data); got != want {
+		t.Errorf("Uploaded data is %v, want %v", got, want)
+	}
+	if got, want := strings.Join(uploader.ranges, ","), "bytes */10,bytes 4-9/10"; got != want {
+		t.Errorf("Content ranges are %v, want %v", got, want)
+	}
+	if len(links) != 0 {
+		t.Errorf("New links %v are requested, want none", links)
+	}
+}
@@ -0,0 +1,299 @@
+package unit
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// writeTree creates the files with the contents in the folder.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	for name, data := range files {
+		localPath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(localPath, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// resultStatuses returns the status or the error of every
+// uploaded file by the relative remote path.
+func resultStatuses(report *yadisk.UploadDirReport, remoteDir string) map[string]string {
+	statuses := make(map[string]string)
+	for _, f := range report.Files {
+		rel, _ := filepath.Rel(remoteDir, f.Path.String())
+		if f.Err != nil {
+			statuses[rel] = "error"
+		} else {
+			statuses[rel] = f.Status.String()
+		}
+	}
+	return statuses
+}
+
+func TestResources_UploadDir(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt":         "a",
+		"sub/b.txt":     "b",
+		"sub/deep/c.go": "c",
+	})
+	os.Mkdir(filepath.Join(dir, "empty"), 0755)
+
+	report, err := client.Resources.UploadDir(context.Background(), dir, "/backup/today", nil)
+	if err != nil {
+		t.Fatalf("Resources.UploadDir returned error %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"/backup/today/a.txt":         "a",
+		"/backup/today/sub/b.txt":     "b",
+		"/backup/today/sub/deep/c.go": "c",
+	} {
+		if got, _ := disk.file(name); got != want {
+			t.Errorf("File %v contents are %q, want %q", name, got, want)
+		}
+	}
+	if disk.dir("/backup/today/empty") {
+		t.Errorf("Empty folder is created")
+	}
+	if got, want := len(report.Files), 3; got != want {
+		t.Errorf("Report has %v files, want %v", got, want)
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Errorf("Failed files are %+v, want none", failed)
+	}
+}
+
+func TestResources_UploadDir_filters(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt":              "a",
+		"a.log":              "a",
+		"sub/b.txt":          "b",
+		"node_modules/c.txt": "c",
+		"sub/skip/d.txt":     "d",
+		"logs/e.log":         "e",
+	})
+	os.Symlink(filepath.Join(dir, "a.log"), filepath.Join(dir, "link.log"))
+
+	opt := &yadisk.UploadDirOptions{
+		Include: []string{"*.txt"},
+		Exclude: []string{"node_modules", "sub/skip"},
+	}
+	report, err := client.Resources.UploadDir(context.Background(), dir, "/backup", opt)
+	if err != nil {
+		t.Fatalf("Resources.UploadDir returned error %v", err)
+	}
+
+	got := resultStatuses(report, "/backup")
+	want := map[string]string{
+		"a.txt":     "transferred",
+		"sub/b.txt": "transferred",
+	}
+	if len(got) != len(want) || got["a.txt"] != want["a.txt"] || got["sub/b.txt"] != want["sub/b.txt"] {
+		t.Errorf("Uploaded files are %v, want %v", got, want)
+	}
+	if disk.dir("/backup/node_modules") {
+		t.Errorf("Excluded folder is created")
+	}
+	if disk.dir("/backup/logs") {
+		t.Errorf("Folder without included files is created")
+	}
+}
+
+func TestResources_UploadDir_bad_pattern(t *testing.T) {
+	setup()
+	defer teardown()
+
+	opt := &yadisk.UploadDirOptions{Include: []string{"["}}
+	_, err := client.Resources.UploadDir(context.Background(), t.TempDir(), "/backup", opt)
+
+	if err == nil {
+		t.Errorf("Resources.UploadDir returned no error for a bad pattern")
+	}
+}
+
+func TestResources_UploadDir_overwrite(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"same.txt":    "same",
+		"changed.txt": "new",
+		"new.txt":     "new",
+	})
+
+	tests := []struct {
+		overwrite yadisk.OverwritePolicy
+		want      map[string]string
+	}{
+		{yadisk.OverwriteNever, map[string]string{
+			"same.txt":    "skipped",
+			"changed.txt": "skipped",
+			"new.txt":     "transferred",
+		}},
+		{yadisk.OverwriteChanged, map[string]string{
+			"same.txt":    "skipped",
+			"changed.txt": "transferred",
+			"new.txt":     "transferred",
+		}},
+		{yadisk.OverwriteAlways, map[string]string{
+			"same.txt":    "transferred",
+			"changed.txt": "transferred",
+			"new.txt":     "transferred",
+		}},
+	}
+
+	for _, tt := range tests {
+		setup()
+		disk := newFakeDisk()
+		disk.addFile("/backup/same.txt", "same", time.Time{})
+		disk.addFile("/backup/changed.txt", "old", time.Time{})
+
+		opt := &yadisk.UploadDirOptions{Overwrite: tt.overwrite, Concurrency: 1}
+		report, err := client.Resources.UploadDir(context.Background(), dir, "/backup", opt)
+		if err != nil {
+			t.Fatalf("Resources.UploadDir returned error %v", err)
+		}
+
+		got := resultStatuses(report, "/backup")
+		for name, want := range tt.want {
+			if got[name] != want {
+				t.Errorf("Policy %v: file %v is %v, want %v", tt.overwrite, name, got[name], want)
+			}
+		}
+		wantChanged := "old"
+		if tt.overwrite != yadisk.OverwriteNever {
+			wantChanged = "new"
+		}
+		if data, _ := disk.file("/backup/changed.txt"); data != wantChanged {
+			t.Errorf("Policy %v: changed file is %q, want %q", tt.overwrite, data, wantChanged)
+		}
+
+		disk.close()
+		teardown()
+	}
+}
+
+func TestResources_UploadDir_symlinks(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"data/a.txt": "a",
+	})
+	if err := os.Symlink(filepath.Join(dir, "data"), filepath.Join(dir, "link")); err != nil {
+		t.Skipf("Symbolic links are not supported: %v", err)
+	}
+	os.Symlink(dir, filepath.Join(dir, "data", "loop"))
+
+	tests := []struct {
+		symlinks yadisk.SymlinkPolicy
+		want     map[string]string
+	}{
+		{yadisk.SymlinksSkip, map[string]string{
+			"data/a.txt": "transferred",
+			"data/loop":  "skipped",
+			"link":       "skipped",
+		}},
+		{yadisk.SymlinksFollow, map[string]string{
+			"data/a.txt": "transferred",
+			"data/loop":  "skipped",
+			"link/a.txt": "transferred",
+			"link/loop":  "skipped",
+		}},
+	}
+
+	for _, tt := range tests {
+		setup()
+		disk := newFakeDisk()
+
+		opt := &yadisk.UploadDirOptions{Symlinks: tt.symlinks}
+		report, err := client.Resources.UploadDir(context.Background(), dir, "/backup", opt)
+		if err != nil {
+			t.Fatalf("Resources.UploadDir returned error %v", err)
+		}
+
+		got := resultStatuses(report, "/backup")
+		if len(got) != len(tt.want) {
+			t.Errorf("Policy %v: files are %v, want %v", tt.symlinks, got, tt.want)
+		}
+		for name, want := range tt.want {
+			if got[name] != want {
+				t.Errorf("Policy %v: file %v is %v, want %v", tt.symlinks, name, got[name], want)
+			}
+		}
+
+		disk.close()
+		teardown()
+	}
+}
+
+func TestResources_UploadDir_file_failures(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	// A folder can't be overwritten with a file.
+	disk.addFile("/backup/a.txt/foo", "foo", time.Time{})
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+	})
+
+	opt := &yadisk.UploadDirOptions{Overwrite: yadisk.OverwriteAlways}
+	report, err := client.Resources.UploadDir(context.Background(), dir, "/backup", opt)
+	if err != nil {
+		t.Fatalf("Resources.UploadDir returned error %v", err)
+	}
+
+	got := resultStatuses(report, "/backup")
+	if got["a.txt"] != "error" || got["b.txt"] != "transferred" {
+		t.Errorf("Uploaded files are %v, want a.txt failed and b.txt transferred", got)
+	}
+	if failed := report.Failed(); len(failed) != 1 {
+		t.Errorf("Failed files are %+v, want one", failed)
+	}
+}
+
+func TestResources_UploadDir_folder_failure(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	// The remote folder exists as a file, so the upload fails.
+	disk.addFile("/backup/sub", "file", time.Time{})
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt":     "a",
+		"sub/b.txt": "b",
+	})
+
+	_, err := client.Resources.UploadDir(context.Background(), dir, "/backup", nil)
+
+	if err == nil {
+		t.Errorf("Resources.UploadDir returned no error for a failed folder")
+	}
+}
@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// newUsageDisk returns the fake Disk with a small tree in /root.
+func newUsageDisk() *fakeDisk {
+	disk := newFakeDisk()
+	now := time.Now()
+	disk.addFile("/root/photos/2020/a.jpg", strings.Repeat("a", 100), now)
+	disk.addFile("/root/photos/2020/b.png", strings.Repeat("b", 50), now)
+	disk.addFile("/root/photos/2021/c.jpg", strings.Repeat("c", 200), now)
+	disk.addFile("/root/docs/README", strings.Repeat("d", 10), now)
+	disk.addFile("/root/e.jpg", strings.Repeat("e", 5), now)
+	return disk
+}
+
+func TestResources_Usage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newUsageDisk()
+	defer disk.close()
+
+	report, err := client.Resources.Usage(context.Background(), "/root", &yadisk.UsageOptions{Top: 2})
+	if err != nil {
+		t.Fatalf("Usage returned error: %v", err)
+	}
+
+	root := report.Root
+	if root.Path != "/root" || root.Size != 365 || root.Files != 5 || root.Folders != 4 {
+		t.Errorf("Root usage is %s %d bytes, %d files, %d folders, want /root 365 bytes, 5 files, 4 folders",
+			root.Path, root.Size, root.Files, root.Folders)
+	}
+	if want := map[string]yadisk.UsageCount{"image": {Size: 355, Files: 4}, "": {Size: 10, Files: 1}}; !reflect.DeepEqual(root.MediaTypes, want) {
+		t.Errorf("Root media types are %v, want %v", root.MediaTypes, want)
+	}
+	if got, want := root.MimeTypes["image/jpeg"], (yadisk.UsageCount{Size: 305, Files: 3}); got != want {
+		t.Errorf("Root image/jpeg usage is %v, want %v", got, want)
+	}
+
+	var names []string
+	for _, child := range root.Children {
+		names = append(names, child.Name)
+	}
+	if want := []string{"docs", "photos"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Root children are %v, want %v", names, want)
+	}
+	photos := root.Children[1]
+	if photos.Path != "/root/photos" || photos.Size != 350 || photos.Files != 3 || photos.Folders != 2 || len(photos.Children) != 2 {
+		t.Errorf("Photos usage is %+v", photos)
+	}
+
+	wantFiles := []yadisk.UsageEntry{{Path: "/root/photos/2021/c.jpg", Size: 200}, {Path: "/root/photos/2020/a.jpg", Size: 100}}
+	if !reflect.DeepEqual(report.LargestFiles, wantFiles) {
+		t.Errorf("Largest files are %v, want %v", report.LargestFiles, wantFiles)
+	}
+	wantFolders := []yadisk.UsageEntry{{Path: "/root/photos", Size: 350}, {Path: "/root/photos/2021", Size: 200}}
+	if !reflect.DeepEqual(report.LargestFolders, wantFolders) {
+		t.Errorf("Largest folders are %v, want %v", report.LargestFolders, wantFolders)
+	}
+
+	// The report survives a JSON round trip.
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	decoded := new(yadisk.UsageReport)
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if !decoded.Time.Equal(report.Time) || !reflect.DeepEqual(decoded.Root, report.Root) {
+		t.Errorf("Decoded report is %s, want %+v", data, report)
+	}
+	if !strings.Contains(string(data), `"path":"/root/photos","name":"photos","size":350,"files":3,"folders":2`) {
+		t.Errorf("Encoded report %s doesn't have the photos usage", data)
+	}
+}
+
+func TestResources_Usage_MaxDepth(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newUsageDisk()
+	defer disk.close()
+
+	report, err := client.Resources.Usage(context.Background(), "/root", &yadisk.UsageOptions{MaxDepth: 1, Top: -1})
+	if err != nil {
+		t.Fatalf("Usage returned error: %v", err)
+	}
+	photos := report.Root.Children[1]
+	if photos.Size != 350 || photos.Files != 3 || photos.Folders != 2 || photos.Children != nil {
+		t.Errorf("Photos usage is %+v, want the subfolders counted in it", photos)
+	}
+	if report.Root.Size != 365 || report.Root.Folders != 4 {
+		t.Errorf("Root usage is %+v", report.Root)
+	}
+	if report.LargestFiles != nil || report.LargestFolders != nil {
+		t.Errorf("Largest are %v, %v, want none", report.LargestFiles, report.LargestFolders)
+	}
+}
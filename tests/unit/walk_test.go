@@ -0,0 +1,172 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// newWalkDisk returns the fake Disk with a small tree in /root
+// and a folder larger than one listing page.
+func newWalkDisk() *fakeDisk {
+	disk := newFakeDisk()
+	now := time.Now()
+	disk.addFile("/root/a/1.txt", "1", now)
+	disk.addFile("/root/a/b/2.txt", "2", now)
+	disk.addFile("/root/c.txt", "c", now)
+	disk.addFile("/root/d/3.txt", "3", now)
+	for i := 0; i < 120; i++ {
+		disk.addFile(fmt.Sprintf("/root/e/%03d.txt", i), "x", now)
+	}
+	return disk
+}
+
+// walkPaths walks the root and returns the visited paths.
+func walkPaths(t *testing.T, root string, opt *yadisk.WalkOptions, skip func(string) error) []string {
+	var paths []string
+	err := client.Resources.Walk(context.Background(), yadisk.Path(root), func(path yadisk.Path, r *yadisk.Resource, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path.String())
+		if skip != nil {
+			return skip(path.String())
+		}
+		return nil
+	}, opt)
+	if err != nil {
+		t.Fatalf("Walk returned error: %v", err)
+	}
+	return paths
+}
+
+func TestResources_Walk(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newWalkDisk()
+	defer disk.close()
+
+	paths := walkPaths(t, "/root", &yadisk.WalkOptions{Ordered: true}, nil)
+	want := []string{"/root", "/root/a", "/root/a/1.txt", "/root/a/b", "/root/a/b/2.txt", "/root/c.txt", "/root/d", "/root/d/3.txt", "/root/e"}
+	if !reflect.DeepEqual(paths[:len(want)], want) {
+		t.Errorf("Walk visited %v, want %v first", paths[:len(want)], want)
+	}
+	if len(paths) != len(want)+120 || paths[len(paths)-1] != "/root/e/119.txt" {
+		t.Errorf("Walk visited %d paths ending with %s, want %d ending with /root/e/119.txt",
+			len(paths), paths[len(paths)-1], len(want)+120)
+	}
+
+	parallel := walkPaths(t, "/root", &yadisk.WalkOptions{Ordered: true, Concurrency: 4}, nil)
+	if !reflect.DeepEqual(parallel, paths) {
+		t.Errorf("Walk with parallel listing visited %v, want %v", parallel, paths)
+	}
+}
+
+func TestResources_Walk_skip(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newWalkDisk()
+	defer disk.close()
+
+	paths := walkPaths(t, "/root", nil, func(path string) error {
+		switch path {
+		case "/root/a", "/root/e":
+			return yadisk.SkipDir
+		case "/root/d/3.txt":
+			return yadisk.SkipAll
+		}
+		return nil
+	})
+	want := []string{"/root", "/root/a", "/root/c.txt", "/root/d", "/root/d/3.txt"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Walk visited %v, want %v", paths, want)
+	}
+
+	// SkipDir for a file skips the rest of its folder.
+	paths = walkPaths(t, "/root/a", &yadisk.WalkOptions{Concurrency: 2}, func(path string) error {
+		if path == "/root/a/1.txt" {
+			return yadisk.SkipDir
+		}
+		return nil
+	})
+	if want := []string{"/root/a", "/root/a/1.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Walk visited %v, want %v", paths, want)
+	}
+}
+
+func TestResources_Walk_MaxDepth(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newWalkDisk()
+	defer disk.close()
+
+	paths := walkPaths(t, "/root", &yadisk.WalkOptions{MaxDepth: 1}, nil)
+	want := []string{"/root", "/root/a", "/root/c.txt", "/root/d", "/root/e"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Walk visited %v, want %v", paths, want)
+	}
+}
+
+func TestResources_Walk_errors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	err := client.Resources.Walk(context.Background(), "/missing", func(path yadisk.Path, r *yadisk.Resource, err error) error {
+		if r != nil || path != "/missing" {
+			t.Errorf("WalkFunc called with %s, %v", path, r)
+		}
+		return err
+	}, nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Walk of missing root returned %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestResources_Walk_fields(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var mu sync.Mutex
+	var fields []string
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fields = append(fields, r.URL.Query().Get("fields"))
+		mu.Unlock()
+		if r.URL.Query().Get("offset") == "" && !strings.Contains(r.URL.Query().Get("fields"), "_embedded") {
+			fmt.Fprint(w, `{"name": "root", "path": "disk:/root", "type": "dir"}`)
+			return
+		}
+		fmt.Fprint(w, `{"_embedded": {"total": 1, "items": [{"name": "a.txt", "path": "disk:/root/a.txt", "type": "file", "size": 1}]}}`)
+	})
+
+	var sizes []uint
+	err := client.Resources.Walk(context.Background(), "/root", func(path yadisk.Path, r *yadisk.Resource, err error) error {
+		sizes = append(sizes, r.Size)
+		return err
+	}, &yadisk.WalkOptions{Fields: []string{"size"}})
+	if err != nil {
+		t.Fatalf("Walk returned error: %v", err)
+	}
+	want := []string{
+		"name,path,type,size",
+		"_embedded.items.name,_embedded.items.path,_embedded.items.type,_embedded.items.size,_embedded.total",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Walk requested fields %q, want %q", fields, want)
+	}
+	if !reflect.DeepEqual(sizes, []uint{0, 1}) {
+		t.Errorf("Walk visited sizes %v, want [0 1]", sizes)
+	}
+}
@@ -0,0 +1,272 @@
+package unit
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+	"github.com/chibisov/go-yadisk/yadisk/webdavfs"
+)
+
+// newTestGateway starts the WebDAV gateway over the fake Disk folder
+// /dav, recording the tokens the clients are created with.
+func newTestGateway() (*httptest.Server, *fakeDisk, *[]string) {
+	disk := newFakeDisk()
+	disk.addFile("/dav/docs/readme.txt", "hello, world", time.Now())
+
+	var mu sync.Mutex
+	var tokens []string
+	gateway := webdavfs.NewHandler("/dav", &webdavfs.HandlerOptions{
+		Prefix: "/webdav",
+		NewClient: func(token string) *yadisk.Client {
+			mu.Lock()
+			tokens = append(tokens, token)
+			mu.Unlock()
+			c := yadisk.NewClient(token)
+			c.BaseURL = client.BaseURL
+			return c
+		},
+	})
+	return httptest.NewServer(gateway), disk, &tokens
+}
+
+// davRequest sends the WebDAV request with the token
+// and returns the response status and body.
+func davRequest(t *testing.T, method, url, token string, body io.Reader, header map[string]string) (int, string) {
+	req, _ := http.NewRequest(method, url, body)
+	if token != "" {
+		req.SetBasicAuth("user", token)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s returned error: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestWebDAV_auth(t *testing.T) {
+	setup()
+	defer teardown()
+
+	gw, disk, tokens := newTestGateway()
+	defer gw.Close()
+	defer disk.close()
+
+	if status, _ := davRequest(t, "GET", gw.URL+"/webdav/docs/readme.txt", "", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("GET without credentials returned %d, want %d", status, http.StatusUnauthorized)
+	}
+	davRequest(t, "GET", gw.URL+"/webdav/docs/readme.txt", "TOKEN1", nil, nil)
+	davRequest(t, "GET", gw.URL+"/webdav/docs/readme.txt", "TOKEN2", nil, nil)
+	if got := strings.Join(*tokens, ","); got != "TOKEN1,TOKEN2" {
+		t.Errorf("Clients created with tokens %s, want TOKEN1,TOKEN2", got)
+	}
+}
+
+func TestWebDAV_get_put(t *testing.T) {
+	setup()
+	defer teardown()
+
+	gw, disk, _ := newTestGateway()
+	defer gw.Close()
+	defer disk.close()
+
+	status, body := davRequest(t, "GET", gw.URL+"/webdav/docs/readme.txt", "TOKEN", nil, nil)
+	if status != http.StatusOK || body != "hello, world" {
+		t.Errorf("GET returned %d %q", status, body)
+	}
+	status, body = davRequest(t, "GET", gw.URL+"/webdav/docs/readme.txt", "TOKEN", nil, map[string]string{
+		"Range": "bytes=7-",
+	})
+	if status != http.StatusPartialContent || body != "world" {
+		t.Errorf("GET with range returned %d %q", status, body)
+	}
+
+	status, _ = davRequest(t, "PUT", gw.URL+"/webdav/docs/new.txt", "TOKEN", strings.NewReader("new file"), nil)
+	if status != http.StatusCreated {
+		t.Errorf("PUT returned %d, want %d", status, http.StatusCreated)
+	}
+	if got, _ := disk.file("/dav/docs/new.txt"); got != "new file" {
+		t.Errorf("Uploaded file contains %q", got)
+	}
+	status, _ = davRequest(t, "PUT", gw.URL+"/webdav/missing/new.txt", "TOKEN", strings.NewReader("x"), nil)
+	if status != http.StatusConflict {
+		t.Errorf("PUT into missing folder returned %d, want %d", status, http.StatusConflict)
+	}
+}
+
+func TestWebDAV_propfind(t *testing.T) {
+	setup()
+	defer teardown()
+
+	gw, disk, _ := newTestGateway()
+	defer gw.Close()
+	defer disk.close()
+	for i := 0; i < 150; i++ {
+		disk.addFile(fmt.Sprintf("/dav/many/%03d.txt", i), "x", time.Now())
+	}
+
+	status, body := davRequest(t, "PROPFIND", gw.URL+"/webdav/many/", "TOKEN", nil, map[string]string{
+		"Depth": "1",
+	})
+	if status != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND returned %d, want %d", status, http.StatusMultiStatus)
+	}
+	if got := strings.Count(body, "<D:response>"); got != 151 {
+		t.Errorf("PROPFIND listed %d resources, want 151", got)
+	}
+	if !strings.Contains(body, "/webdav/many/149.txt") {
+		t.Errorf("PROPFIND response misses the last page")
+	}
+}
+
+func TestWebDAV_mkcol_copy_move_delete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	gw, disk, _ := newTestGateway()
+	defer gw.Close()
+	defer disk.close()
+
+	if status, _ := davRequest(t, "MKCOL", gw.URL+"/webdav/backup", "TOKEN", nil, nil); status != http.StatusCreated {
+		t.Errorf("MKCOL returned %d, want %d", status, http.StatusCreated)
+	}
+	if !disk.dir("/dav/backup") {
+		t.Errorf("MKCOL didn't create the folder")
+	}
+
+	status, _ := davRequest(t, "COPY", gw.URL+"/webdav/docs", "TOKEN", nil, map[string]string{
+		"Destination": gw.URL + "/webdav/backup/docs",
+	})
+	if status != http.StatusCreated {
+		t.Errorf("COPY returned %d, want %d", status, http.StatusCreated)
+	}
+	if got, _ := disk.file("/dav/backup/docs/readme.txt"); got != "hello, world" {
+		t.Errorf("Copied file contains %q", got)
+	}
+	status, _ = davRequest(t, "COPY", gw.URL+"/webdav/docs", "TOKEN", nil, map[string]string{
+		"Destination": gw.URL + "/webdav/backup/docs",
+		"Overwrite":   "F",
+	})
+	if status != http.StatusPreconditionFailed {
+		t.Errorf("COPY without overwrite returned %d, want %d", status, http.StatusPreconditionFailed)
+	}
+
+	status, _ = davRequest(t, "MOVE", gw.URL+"/webdav/docs/readme.txt", "TOKEN", nil, map[string]string{
+		"Destination": gw.URL + "/webdav/readme.txt",
+	})
+	if status != http.StatusCreated {
+		t.Errorf("MOVE returned %d, want %d", status, http.StatusCreated)
+	}
+	if _, ok := disk.file("/dav/docs/readme.txt"); ok {
+		t.Errorf("Moved file still exists")
+	}
+	if got, _ := disk.file("/dav/readme.txt"); got != "hello, world" {
+		t.Errorf("Moved file contains %q", got)
+	}
+
+	if status, _ := davRequest(t, "DELETE", gw.URL+"/webdav/backup", "TOKEN", nil, nil); status != http.StatusNoContent {
+		t.Errorf("DELETE returned %d, want %d", status, http.StatusNoContent)
+	}
+	if disk.dir("/dav/backup") {
+		t.Errorf("DELETE didn't remove the folder")
+	}
+	if status, _ := davRequest(t, "DELETE", gw.URL+"/webdav/", "TOKEN", nil, nil); status == http.StatusNoContent {
+		t.Errorf("DELETE of the root succeeded")
+	}
+}
+
+func TestWebDAV_invalid_token(t *testing.T) {
+	requests := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error": "UnauthorizedError"}`)
+	}))
+	defer api.Close()
+
+	gateway := webdavfs.NewHandler("/dav", &webdavfs.HandlerOptions{
+		NewClient: func(token string) *yadisk.Client {
+			c := yadisk.NewClient(token)
+			c.BaseURL, _ = url.Parse(api.URL)
+			return c
+		},
+	})
+	gw := httptest.NewServer(gateway)
+	defer gw.Close()
+
+	for i := 0; i < 2; i++ {
+		if status, _ := davRequest(t, "PROPFIND", gw.URL+"/", "BAD", nil, nil); status != http.StatusUnauthorized {
+			t.Errorf("PROPFIND with invalid token returned %d, want %d", status, http.StatusUnauthorized)
+		}
+	}
+	// The token is checked again, no locks are kept for it.
+	if requests != 2 {
+		t.Errorf("Sent %d requests, want 2", requests)
+	}
+}
+
+// lockBody is the body of the LOCK request for an exclusive write lock.
+const lockBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:lockinfo xmlns:D="DAV:">
+	<D:lockscope><D:exclusive/></D:lockscope>
+	<D:locktype><D:write/></D:locktype>
+</D:lockinfo>`
+
+func TestWebDAV_locks(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/dav/docs/readme.txt", "hello, world", time.Now())
+
+	gateway := webdavfs.NewHandler("/dav", &webdavfs.HandlerOptions{
+		NewClient: func(token string) *yadisk.Client {
+			c := yadisk.NewClient(token)
+			c.BaseURL = client.BaseURL
+			return c
+		},
+		LockIdleTimeout: 100 * time.Millisecond,
+	})
+	gw := httptest.NewServer(gateway)
+	defer gw.Close()
+
+	status, _ := davRequest(t, "LOCK", gw.URL+"/docs", "TOKEN", strings.NewReader(lockBody), nil)
+	if status != http.StatusOK {
+		t.Fatalf("LOCK returned %d, want %d", status, http.StatusOK)
+	}
+
+	// The copy into the locked folder is rejected without the lock token.
+	copyHeader := map[string]string{"Destination": gw.URL + "/docs/copy.txt"}
+	if status, _ := davRequest(t, "COPY", gw.URL+"/docs/readme.txt", "TOKEN", nil, copyHeader); status != http.StatusLocked {
+		t.Errorf("COPY to locked folder returned %d, want %d", status, http.StatusLocked)
+	}
+	if _, ok := disk.file("/dav/docs/copy.txt"); ok {
+		t.Errorf("COPY to locked folder created the file")
+	}
+
+	// The locks of other accounts are separate.
+	if status, _ := davRequest(t, "COPY", gw.URL+"/docs/readme.txt", "OTHER", nil, copyHeader); status != http.StatusCreated {
+		t.Errorf("COPY by other account returned %d, want %d", status, http.StatusCreated)
+	}
+
+	// The locks are dropped when the account is idle.
+	time.Sleep(150 * time.Millisecond)
+	copyHeader["Destination"] = gw.URL + "/docs/copy2.txt"
+	if status, _ := davRequest(t, "COPY", gw.URL+"/docs/readme.txt", "TOKEN", nil, copyHeader); status != http.StatusCreated {
+		t.Errorf("COPY after idle timeout returned %d, want %d", status, http.StatusCreated)
+	}
+}
@@ -0,0 +1,209 @@
+package unit
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk/diskfs"
+)
+
+func TestWriteFS_create(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	wfs := diskfs.NewWriteFS(client, "/backups")
+
+	if err := wfs.MkdirAll("/2020/05", 0755); err != nil {
+		t.Fatalf("MkdirAll returned error: %v", err)
+	}
+	f, err := wfs.Create("/2020/05/db.sql")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := f.WriteString("CREATE TABLE "); err != nil {
+		t.Fatalf("WriteString returned error: %v", err)
+	}
+	if _, err := f.WriteAt([]byte("t;"), 13); err != nil {
+		t.Fatalf("WriteAt at the end returned error: %v", err)
+	}
+	if _, err := f.WriteAt([]byte("x"), 0); !errors.Is(err, diskfs.ErrUnsupported) {
+		t.Errorf("WriteAt at 0 returned %v, want %v", err, diskfs.ErrUnsupported)
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, diskfs.ErrUnsupported) {
+		t.Errorf("Read returned %v, want %v", err, diskfs.ErrUnsupported)
+	}
+	if pos, err := f.Seek(0, io.SeekCurrent); err != nil || pos != 15 {
+		t.Errorf("Seek returned %v, %v, want 15", pos, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := f.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("second Close returned %v, want %v", err, fs.ErrClosed)
+	}
+
+	if got, _ := disk.file("/backups/2020/05/db.sql"); got != "CREATE TABLE t;" {
+		t.Errorf("Uploaded data is %q", got)
+	}
+	info, err := wfs.Stat("2020/05/db.sql")
+	if err != nil || info.Size() != 15 {
+		t.Errorf("Stat returned %v, %v, want size 15", info, err)
+	}
+
+	r, err := wfs.Open("2020/05/db.sql")
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer r.Close()
+	if data, err := ioutil.ReadAll(r); err != nil || string(data) != "CREATE TABLE t;" {
+		t.Errorf("Read returned %q, %v", data, err)
+	}
+	if _, err := r.Write([]byte("x")); err == nil {
+		t.Errorf("Write to a file opened for reading returned no error")
+	}
+}
+
+func TestWriteFS_OpenFile_flags(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/old.txt", "old", time.Now())
+	wfs := diskfs.NewWriteFS(client, "/")
+
+	tests := []struct {
+		name string
+		flag int
+		want error
+	}{
+		{"old.txt", os.O_WRONLY | os.O_APPEND, diskfs.ErrUnsupported},
+		{"old.txt", os.O_WRONLY, diskfs.ErrUnsupported},
+		{"old.txt", os.O_WRONLY | os.O_CREATE | os.O_EXCL, fs.ErrExist},
+		{"new.txt", os.O_WRONLY, fs.ErrNotExist},
+		{"missing/new.txt", os.O_WRONLY | os.O_CREATE, fs.ErrNotExist},
+	}
+	for _, tt := range tests {
+		if _, err := wfs.OpenFile(tt.name, tt.flag, 0644); !errors.Is(err, tt.want) {
+			t.Errorf("OpenFile(%q, %#o) returned %v, want %v", tt.name, tt.flag, err, tt.want)
+		}
+	}
+
+	f, err := wfs.OpenFile("old.txt", os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile with O_TRUNC returned error: %v", err)
+	}
+	f.Write([]byte("new"))
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if got, _ := disk.file("/old.txt"); got != "new" {
+		t.Errorf("Truncated file contains %q, want %q", got, "new")
+	}
+
+	if err := wfs.Chmod("old.txt", 0600); !errors.Is(err, diskfs.ErrUnsupported) {
+		t.Errorf("Chmod returned %v, want %v", err, diskfs.ErrUnsupported)
+	}
+}
+
+func TestWriteFS_mkdir_remove(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/data/a/file.txt", "a", time.Now())
+	wfs := diskfs.NewWriteFS(client, "/data")
+
+	if err := wfs.Mkdir("a", 0755); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Mkdir of existing folder returned %v, want %v", err, fs.ErrExist)
+	}
+	if err := wfs.Mkdir("b", 0755); err != nil || !disk.dir("/data/b") {
+		t.Errorf("Mkdir returned %v", err)
+	}
+	if err := wfs.Remove("a"); err == nil {
+		t.Errorf("Remove of non-empty folder returned no error")
+	}
+	if err := wfs.Remove("b"); err != nil || disk.dir("/data/b") {
+		t.Errorf("Remove of empty folder returned %v", err)
+	}
+	if err := wfs.Remove("b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Remove of missing folder returned %v, want %v", err, fs.ErrNotExist)
+	}
+	if err := wfs.RemoveAll("a"); err != nil || disk.dir("/data/a") {
+		t.Errorf("RemoveAll returned %v", err)
+	}
+	if err := wfs.RemoveAll("a"); err != nil {
+		t.Errorf("RemoveAll of missing folder returned %v", err)
+	}
+}
+
+func TestWriteFS_RemoveAll_async(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var polls int
+	mux.HandleFunc("/v1/disk/resources", func(w http.ResponseWriter, r *http.Request) {
+		if m := "DELETE"; m != r.Method {
+			t.Errorf("Request method = %v, want %v", r.Method, m)
+		}
+		if got := r.URL.Query().Get("permanently"); got != "true" {
+			t.Errorf("permanently = %q, want %q", got, "true")
+		}
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"href": "`+server.URL+`/v1/disk/operations/1", "method": "GET"}`)
+	})
+	mux.HandleFunc("/v1/disk/operations/1", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 2 {
+			io.WriteString(w, `{"status": "in-progress"}`)
+			return
+		}
+		io.WriteString(w, `{"status": "success"}`)
+	})
+
+	wfs := diskfs.NewWriteFS(client, "/data")
+	wfs.Permanently = true
+	if err := wfs.RemoveAll("a"); err != nil {
+		t.Fatalf("RemoveAll returned error: %v", err)
+	}
+	if polls != 2 {
+		t.Errorf("Operation status requested %d times, want 2", polls)
+	}
+}
+
+func TestWriteFS_Rename(t *testing.T) {
+	setup()
+	defer teardown()
+
+	disk := newFakeDisk()
+	defer disk.close()
+	disk.addFile("/data/a/file.txt", "a", time.Now())
+	disk.addFile("/data/other.txt", "other", time.Now())
+	disk.addFile("/data/c/keep.txt", "c", time.Now())
+	wfs := diskfs.NewWriteFS(client, "/data")
+
+	if err := wfs.Rename("a/file.txt", "other.txt"); err != nil {
+		t.Fatalf("Rename over a file returned error: %v", err)
+	}
+	if got, _ := disk.file("/data/other.txt"); got != "a" {
+		t.Errorf("Renamed file contains %q, want %q", got, "a")
+	}
+	if err := wfs.Rename("a", "b"); err != nil || !disk.dir("/data/b") || disk.dir("/data/a") {
+		t.Errorf("Rename of a folder returned %v", err)
+	}
+	if err := wfs.Rename("other.txt", "c"); err == nil {
+		t.Errorf("Rename over a folder returned no error")
+	}
+	if err := wfs.Rename("missing", "d"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Rename of missing file returned %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chibisov/go-yadisk/yadisk"
 )
@@ -37,7 +39,7 @@ func TestNewRequest(t *testing.T) {
 		Login string `json:"login"`
 	}
 
-	inURL, outURL := "disk", "https://cloud-api.yandex.net/v1/disk/"
+	inURL, outURL := "disk/", "https://cloud-api.yandex.net/v1/disk/"
 	inBody, outBody := &User{Login: "sosisa"}, `{"login":"sosisa"}`+"\n"
 	req, _ := c.NewRequest("GET", inURL, inBody)
 
@@ -87,6 +89,75 @@ func TestNewRequest_bad_url(t *testing.T) {
 	}
 }
 
+func TestNewRequest_path_without_trailing_slash(t *testing.T) {
+	c := yadisk.NewClient("ACCESS_TOKEN")
+
+	inURL, outURL := "disk/resources", "https://cloud-api.yandex.net/v1/disk/resources"
+	req, _ := c.NewRequest("GET", inURL, nil)
+
+	if got, want := req.URL.String(), outURL; got != want {
+		t.Errorf("NewRequest(%q) URL is %v, want %v", inURL, got, want)
+	}
+}
+
+func TestNewRequest_with_query(t *testing.T) {
+	c := yadisk.NewClient("ACCESS_TOKEN")
+
+	inURL := "disk/resources?path=%2Ffoo"
+	outURL := "https://cloud-api.yandex.net/v1/disk/resources?limit=10&path=%2Ffoo"
+	req, _ := c.NewRequest("GET", inURL, nil, yadisk.WithQuery(url.Values{"limit": {"10"}}))
+
+	if got, want := req.URL.String(), outURL; got != want {
+		t.Errorf("NewRequest(%q) URL is %v, want %v", inURL, got, want)
+	}
+}
+
+func TestNewRequest_absolute_url(t *testing.T) {
+	c := yadisk.NewClient("ACCESS_TOKEN")
+
+	inURL := "https://uploader1d.dst.yandex.net:443/upload-target/123"
+	req, _ := c.NewRequest("PUT", inURL, nil)
+
+	// Check that absolute URL is used as is.
+	if got, want := req.URL.String(), inURL; got != want {
+		t.Errorf("NewRequest(%q) URL is %v, want %v", inURL, got, want)
+	}
+
+	// Check that authorization key isn't sent to other hosts.
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization header is %v, want empty", got)
+	}
+}
+
+func TestNewRequest_reader_body(t *testing.T) {
+	c := yadisk.NewClient("ACCESS_TOKEN")
+
+	req, _ := c.NewRequest("PUT", "disk/", strings.NewReader("raw data"))
+
+	body, _ := ioutil.ReadAll(req.Body)
+	if got, want := string(body), "raw data"; got != want {
+		t.Errorf("NewRequest Body is %v, want %v", got, want)
+	}
+	if got, want := req.Header.Get("Content-Type"), "application/octet-stream"; got != want {
+		t.Errorf("Content-Type header is %v, want %v", got, want)
+	}
+}
+
+func TestNewRequest_with_content_type(t *testing.T) {
+	c := yadisk.NewClient("ACCESS_TOKEN")
+
+	req, _ := c.NewRequest(
+		"PUT",
+		"disk/",
+		strings.NewReader("raw data"),
+		yadisk.WithContentType("text/plain"),
+	)
+
+	if got, want := req.Header.Get("Content-Type"), "text/plain"; got != want {
+		t.Errorf("Content-Type header is %v, want %v", got, want)
+	}
+}
+
 // If a nil body is passed to github.NewRequest, make sure that nil is also
 // passed to http.NewRequest. In most cases, passing an io.Reader that returns
 // no content is fine, since there is no difference between an HTTP request
@@ -118,13 +189,31 @@ func TestDo(t *testing.T) {
 	})
 
 	req, _ := client.NewRequest("GET", "/", nil)
-	client.Do(context.Background(), req, buf)
+	client.Do(req, buf)
 
 	if got, want := buf.String(), `{"A":"a"}`; got != want {
 		t.Errorf("Response body = %v, want %v", got, want)
 	}
 }
 
+func TestDo_canceled_context(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request with canceled context was sent")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := client.NewRequestWithContext(ctx, "GET", "/", nil)
+	_, err := client.Do(req, nil)
+
+	if err != context.Canceled {
+		t.Errorf("Do returned error %v, want %v", err, context.Canceled)
+	}
+}
+
 func TestDo_with_struct(t *testing.T) {
 	setup()
 	defer teardown()
@@ -142,7 +231,7 @@ func TestDo_with_struct(t *testing.T) {
 
 	req, _ := client.NewRequest("GET", "/", nil)
 	body := new(foo)
-	client.Do(context.Background(), req, body)
+	client.Do(req, body)
 
 	want := &foo{A: "a"}
 	if !reflect.DeepEqual(body, want) {
@@ -159,7 +248,7 @@ func TestDo_http_error_not_json(t *testing.T) {
 	})
 
 	req, _ := client.NewRequest("GET", "/", nil)
-	_, err := client.Do(context.Background(), req, nil)
+	_, err := client.Do(req, nil)
 
 	if err == nil {
 		t.Error("Expected HTTP 400 error.")
@@ -192,7 +281,7 @@ func TestDo_http_error_json(t *testing.T) {
 	})
 
 	req, _ := client.NewRequest("GET", "/", nil)
-	_, err := client.Do(context.Background(), req, nil)
+	_, err := client.Do(req, nil)
 
 	if err == nil {
 		t.Error("Expected HTTP 409 error.")
@@ -209,3 +298,115 @@ func TestDo_http_error_json(t *testing.T) {
 		t.Errorf("Error text = '%s', want '%s'", got, want)
 	}
 }
+
+func TestDo_retries(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "service unavailable", 503)
+			return
+		}
+		fmt.Fprint(w, `{"A":"a"}`)
+	})
+
+	client.MaxRetries = 2
+	req, _ := client.NewRequest("GET", "/", nil)
+	buf := &bytes.Buffer{}
+	_, err := client.Do(req, buf)
+
+	if err != nil {
+		t.Errorf("Do returned error %v", err)
+	}
+	if got, want := calls, 3; got != want {
+		t.Errorf("Request was sent %v times, want %v", got, want)
+	}
+	if got, want := buf.String(), `{"A":"a"}`; got != want {
+		t.Errorf("Response body = %v, want %v", got, want)
+	}
+}
+
+func TestDo_retries_exhausted(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "too many requests", 429)
+	})
+
+	client.MaxRetries = 1
+	req, _ := client.NewRequest("GET", "/", nil)
+	_, err := client.Do(req, nil)
+
+	if _, ok := err.(*yadisk.APIError); !ok {
+		t.Errorf("Expected a yadisk.APIError error; got %#v", err)
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("Request was sent %v times, want %v", got, want)
+	}
+}
+
+func TestDo_retries_long_retry_after(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "too many requests", 429)
+	})
+
+	client.MaxRetries = 3
+	req, _ := client.NewRequest("GET", "/", nil)
+	start := time.Now()
+	response, err := client.Do(req, nil)
+
+	if _, ok := err.(*yadisk.APIError); !ok {
+		t.Errorf("Expected a yadisk.APIError error; got %#v", err)
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("Request was sent %v times, want %v", got, want)
+	}
+	if got := response.Header.Get("Retry-After"); got != "3600" {
+		t.Errorf("Response Retry-After = %v, want 3600", got)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Do waited %v", elapsed)
+	}
+}
+
+func TestDo_retries_post(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		if calls == 1 {
+			http.Error(w, "too many requests", 429)
+			return
+		}
+		http.Error(w, "internal server error", 500)
+	})
+
+	// The throttled POST is retried, the failed one isn't.
+	client.MaxRetries = 3
+	req, _ := client.NewRequest("POST", "/", nil)
+	_, err := client.Do(req, nil)
+
+	if apiErr, ok := err.(*yadisk.APIError); !ok || apiErr.StatusCode != 500 {
+		t.Errorf("Expected a yadisk.APIError with status 500; got %#v", err)
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("Request was sent %v times, want %v", got, want)
+	}
+}
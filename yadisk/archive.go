@@ -0,0 +1,567 @@
+package yadisk
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Zip format constants used by the streaming extractor.
+const (
+	zipLocalHeaderSignature    = 0x04034b50
+	zipDataDescriptorSignature = 0x08074b50
+	zipCentralHeaderSignature  = 0x02014b50
+	zipEndSignature            = 0x06054b50
+
+	zipFlagEncrypted      = 0x1
+	zipFlagDataDescriptor = 0x8
+
+	zipMethodStore   = 0
+	zipMethodDeflate = 8
+
+	zipExtraZip64 = 0x0001
+)
+
+var (
+	// ErrArchiveLimit is returned by ExtractArchive when the archive
+	// exceeds one of the limits set in the options.
+	ErrArchiveLimit = errors.New("yadisk: archive limit exceeded")
+
+	// ErrInvalidArchive is returned by ExtractArchive for a malformed
+	// or unsupported archive, or for an entry whose name points
+	// outside of the destination folder.
+	ErrInvalidArchive = errors.New("yadisk: invalid archive")
+)
+
+// ArchiveOptions specifies the optional parameters to the
+// ResourcesService.DownloadArchive and ResourcesService.ExtractArchive
+// methods. The limits are used by ExtractArchive only.
+type ArchiveOptions struct {
+	// The key of a public folder. If set, the archive of the public
+	// folder is requested, and the path is relative to it. An empty
+	// path means the whole public folder.
+	PublicKey string
+
+	// Progress, if set, is called with the number of archive bytes
+	// downloaded so far. The total size is unknown.
+	Progress ProgressFunc
+
+	// The minimal time between progress reports. Defaults to 500ms.
+	ProgressInterval time.Duration
+
+	// Bandwidth, if set, limits the download rate in addition
+	// to the Client Bandwidth.
+	Bandwidth *BandwidthLimiter
+
+	// The maximal uncompressed size of a single file and of all
+	// the files, and the maximal number of entries. Zero means
+	// no limit.
+	MaxFileSize  int64
+	MaxTotalSize int64
+	MaxFiles     int
+}
+
+// GetPublicDownloadLink requests the URL for downloading the file
+// at the path within the public resource with the key. An empty path
+// means the public resource itself. For a folder the link points
+// to the zip archive of its contents.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/public-docpage/#download
+func (s *ResourcesService) GetPublicDownloadLink(
+	ctx context.Context,
+	publicKey string,
+	path Path,
+) (*Link, *Response, error) {
+	params := url.Values{"public_key": {publicKey}}
+	if path != "" {
+		params.Set("path", string(path))
+	}
+
+	url := "disk/public/resources/download"
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "public.download_link"),
+		"GET",
+		url,
+		nil,
+		WithQuery(params),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link := new(Link)
+	resp, err := s.client.Do(req, link)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return link, resp, nil
+}
+
+// DownloadArchive writes the zip archive of the folder at the path
+// to w. The archive is made by Yandex.Disk on the fly and streamed
+// as it's made, so its size is unknown in advance.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/content-docpage/
+func (s *ResourcesService) DownloadArchive(
+	ctx context.Context,
+	path Path,
+	w io.Writer,
+	opt *ArchiveOptions,
+) (*Response, error) {
+	if opt == nil {
+		opt = new(ArchiveOptions)
+	}
+
+	resp, body, err := s.openArchive(ctx, path, opt)
+	if err != nil {
+		return resp, err
+	}
+	defer body.Close()
+
+	_, err = io.Copy(w, body)
+	return resp, err
+}
+
+// ExtractArchive downloads the zip archive of the folder at the path
+// like DownloadArchive and extracts it into the local folder while
+// it's being downloaded, without storing the archive. The paths of
+// the extracted files are returned.
+//
+// Entries with absolute names or names pointing outside of the local
+// folder fail the extraction with ErrInvalidArchive, and exceeding
+// the limits set in the options fails it with ErrArchiveLimit.
+// The files extracted before the failure are left in place.
+// Only the stored and deflated entries are supported.
+func (s *ResourcesService) ExtractArchive(
+	ctx context.Context,
+	path Path,
+	localDir string,
+	opt *ArchiveOptions,
+) ([]string, error) {
+	if opt == nil {
+		opt = new(ArchiveOptions)
+	}
+
+	_, body, err := s.openArchive(ctx, path, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return nil, err
+	}
+	x := &zipExtractor{
+		r:   bufio.NewReader(body),
+		dir: localDir,
+		opt: opt,
+	}
+	err = x.run()
+	return x.files, err
+}
+
+// openArchive requests the archive and returns the response
+// with its body wrapped for the progress and the bandwidth limit.
+func (s *ResourcesService) openArchive(
+	ctx context.Context,
+	path Path,
+	opt *ArchiveOptions,
+) (*Response, io.ReadCloser, error) {
+	var (
+		link *Link
+		resp *Response
+		err  error
+	)
+	if opt.PublicKey != "" {
+		link, resp, err = s.GetPublicDownloadLink(ctx, opt.PublicKey, path)
+	} else {
+		link, resp, err = s.GetDownloadLink(ctx, path)
+	}
+	if err != nil {
+		return resp, nil, err
+	}
+
+	ctx = WithOperation(ctx, "resources.download_archive")
+	req, err := s.client.NewRequestWithContext(ctx, "GET", link.Href, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err = s.client.stream(req)
+	if err != nil {
+		return resp, nil, err
+	}
+
+	tracker := newProgressTracker(opt.Progress, opt.ProgressInterval, -1)
+	body := &archiveBody{
+		Reader: &progressReader{
+			r:       s.client.throttle(ctx, resp.Body, opt.Bandwidth),
+			tracker: tracker,
+		},
+		body:    resp.Body,
+		tracker: tracker,
+		onClose: func(n int64) {
+			s.client.observe(Event{
+				Kind:       EventBytesDownloaded,
+				Operation:  operationFromContext(ctx),
+				StatusCode: resp.StatusCode,
+				Bytes:      n,
+			})
+		},
+	}
+	return resp, body, nil
+}
+
+// archiveBody is the archive response body which reports
+// the final progress and the downloaded bytes when closed.
+type archiveBody struct {
+	io.Reader
+	body    io.Closer
+	tracker *progressTracker
+	onClose func(n int64)
+	read    int64
+}
+
+func (b *archiveBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *archiveBody) Close() error {
+	b.tracker.finish()
+	b.onClose(b.read)
+	return b.body.Close()
+}
+
+// zipExtractor extracts a zip archive read sequentially, relying
+// on the local file headers instead of the central directory
+// at the end of the archive.
+type zipExtractor struct {
+	r     *bufio.Reader
+	dir   string
+	opt   *ArchiveOptions
+	files []string
+	total int64
+	count int
+}
+
+// zipEntry is the local file header of an archive entry.
+type zipEntry struct {
+	name             string
+	flags            uint16
+	method           uint16
+	crc32            uint32
+	compressedSize   uint64
+	uncompressedSize uint64
+	zip64            bool
+}
+
+func (x *zipExtractor) run() error {
+	for {
+		var signature uint32
+		if err := binary.Read(x.r, binary.LittleEndian, &signature); err != nil {
+			return invalidArchive(err)
+		}
+		switch signature {
+		case zipLocalHeaderSignature:
+		case zipCentralHeaderSignature, zipEndSignature:
+			// The entries are over, the rest is the central directory.
+			io.Copy(ioutil.Discard, x.r)
+			return nil
+		default:
+			return fmt.Errorf("%w: unexpected signature %#x", ErrInvalidArchive, signature)
+		}
+
+		entry, err := x.readHeader()
+		if err != nil {
+			return err
+		}
+		if err := x.extract(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// readHeader reads the local file header after the signature.
+func (x *zipExtractor) readHeader() (*zipEntry, error) {
+	var header struct {
+		Version          uint16
+		Flags            uint16
+		Method           uint16
+		ModTime          uint16
+		ModDate          uint16
+		CRC32            uint32
+		CompressedSize   uint32
+		UncompressedSize uint32
+		NameLength       uint16
+		ExtraLength      uint16
+	}
+	if err := binary.Read(x.r, binary.LittleEndian, &header); err != nil {
+		return nil, invalidArchive(err)
+	}
+
+	name := make([]byte, header.NameLength)
+	if _, err := io.ReadFull(x.r, name); err != nil {
+		return nil, invalidArchive(err)
+	}
+	extra := make([]byte, header.ExtraLength)
+	if _, err := io.ReadFull(x.r, extra); err != nil {
+		return nil, invalidArchive(err)
+	}
+
+	entry := &zipEntry{
+		name:             string(name),
+		flags:            header.Flags,
+		method:           header.Method,
+		crc32:            header.CRC32,
+		compressedSize:   uint64(header.CompressedSize),
+		uncompressedSize: uint64(header.UncompressedSize),
+	}
+
+	// The zip64 extra field holds the sizes which don't fit in 32 bits.
+	for len(extra) >= 4 {
+		tag := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		if tag == zipExtraZip64 {
+			entry.zip64 = true
+			field := extra[:size]
+			if header.UncompressedSize == 0xffffffff && len(field) >= 8 {
+				entry.uncompressedSize = binary.LittleEndian.Uint64(field)
+				field = field[8:]
+			}
+			if header.CompressedSize == 0xffffffff && len(field) >= 8 {
+				entry.compressedSize = binary.LittleEndian.Uint64(field)
+			}
+		}
+		extra = extra[size:]
+	}
+
+	if entry.flags&zipFlagEncrypted != 0 {
+		return nil, fmt.Errorf("%w: %s is encrypted", ErrInvalidArchive, entry.name)
+	}
+	return entry, nil
+}
+
+// extract writes the entry data into the local folder.
+func (x *zipExtractor) extract(entry *zipEntry) error {
+	x.count++
+	if x.opt.MaxFiles > 0 && x.count > x.opt.MaxFiles {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveLimit, x.opt.MaxFiles)
+	}
+
+	name := strings.TrimSuffix(entry.name, "/")
+	isDir := name != entry.name
+	localName := filepath.FromSlash(name)
+	if name == "" || strings.Contains(name, `\`) || !filepath.IsLocal(localName) {
+		return fmt.Errorf("%w: unsafe entry name %q", ErrInvalidArchive, entry.name)
+	}
+	localPath := filepath.Join(x.dir, localName)
+	if err := x.checkParents(entry, localName, isDir); err != nil {
+		return err
+	}
+
+	// The data of stored entries can't be found without the size,
+	// unless the descriptor of the empty data follows right away.
+	hasDescriptor := entry.flags&zipFlagDataDescriptor != 0
+	if entry.method == zipMethodStore && hasDescriptor && entry.compressedSize == 0 &&
+		!isDir && !x.emptyDataFollows(entry) {
+		return fmt.Errorf("%w: stored entry %s has no size", ErrInvalidArchive, entry.name)
+	}
+
+	var data io.Reader
+	switch entry.method {
+	case zipMethodStore:
+		data = io.LimitReader(x.r, int64(entry.compressedSize))
+	case zipMethodDeflate:
+		// The deflate stream finds its own end, reading the buffered
+		// reader byte by byte, so the data after it isn't consumed.
+		fr := flate.NewReader(x.r)
+		defer fr.Close()
+		data = fr
+	default:
+		return fmt.Errorf("%w: unsupported compression method %d of %s", ErrInvalidArchive, entry.method, entry.name)
+	}
+
+	var (
+		crc uint32
+		err error
+	)
+	if isDir {
+		if _, err := io.Copy(ioutil.Discard, data); err != nil {
+			return invalidArchive(err)
+		}
+		if err := os.MkdirAll(localPath, 0755); err != nil {
+			return err
+		}
+		crc = 0
+	} else {
+		if crc, err = x.writeFile(localPath, entry, data); err != nil {
+			return err
+		}
+		x.files = append(x.files, localPath)
+	}
+
+	// The checksum is in the data descriptor if it follows the data.
+	if hasDescriptor {
+		if entry.crc32, err = x.readDescriptor(entry); err != nil {
+			return err
+		}
+	}
+	if crc != entry.crc32 {
+		return fmt.Errorf("%w: %s checksum mismatch", ErrInvalidArchive, entry.name)
+	}
+	return nil
+}
+
+// checkParents returns an error if one of the existing folders on
+// the way from the destination folder to the entry is a symbolic link,
+// which could lead outside of the destination folder.
+func (x *zipExtractor) checkParents(entry *zipEntry, localName string, isDir bool) error {
+	dir := filepath.Clean(localName)
+	if !isDir {
+		dir = filepath.Dir(dir)
+	}
+	if dir == "." {
+		return nil
+	}
+	p := x.dir
+	for _, name := range strings.Split(dir, string(filepath.Separator)) {
+		p = filepath.Join(p, name)
+		info, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			// The rest of the folders is created by the extractor.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: entry %q is extracted through a link", ErrInvalidArchive, entry.name)
+		}
+	}
+	return nil
+}
+
+// emptyDataFollows reports whether the entry data is empty: the data
+// descriptor of the empty data, with or without the signature, is
+// followed by the next header.
+func (x *zipExtractor) emptyDataFollows(entry *zipEntry) bool {
+	length := 4 + 8
+	if entry.zip64 {
+		length = 4 + 16
+	}
+	for _, signed := range []bool{true, false} {
+		n := length
+		if signed {
+			n += 4
+		}
+		b, err := x.r.Peek(n + 4)
+		if err != nil {
+			continue
+		}
+		if signed {
+			if binary.LittleEndian.Uint32(b) != zipDataDescriptorSignature {
+				continue
+			}
+			b = b[4:]
+		}
+		// The CRC-32 checksum and the sizes of the empty data are zero.
+		if !bytes.Equal(b[:length], make([]byte, length)) {
+			continue
+		}
+		switch binary.LittleEndian.Uint32(b[length:]) {
+		case zipLocalHeaderSignature, zipCentralHeaderSignature:
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile writes the entry data to the local file, checking
+// the limits. The CRC-32 checksum of the data is returned.
+func (x *zipExtractor) writeFile(localPath string, entry *zipEntry, data io.Reader) (uint32, error) {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return 0, err
+	}
+
+	// Replace the existing file instead of writing through a link.
+	if err := os.Remove(localPath); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	f, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	limit := int64(-1)
+	if x.opt.MaxFileSize > 0 {
+		limit = x.opt.MaxFileSize
+	}
+	if x.opt.MaxTotalSize > 0 && (limit < 0 || x.opt.MaxTotalSize-x.total < limit) {
+		limit = x.opt.MaxTotalSize - x.total
+	}
+	if limit >= 0 {
+		// Read one byte more to detect the exceeded limit.
+		data = io.LimitReader(data, limit+1)
+	}
+
+	h := crc32.NewIEEE()
+	n, err := io.Copy(io.MultiWriter(f, h), data)
+	x.total += n
+	if err != nil {
+		return 0, invalidArchive(err)
+	}
+	if limit >= 0 && n > limit {
+		return 0, fmt.Errorf("%w: %s is too big", ErrArchiveLimit, entry.name)
+	}
+	return h.Sum32(), f.Close()
+}
+
+// readDescriptor reads the data descriptor following the entry
+// data and returns the CRC-32 checksum from it.
+func (x *zipExtractor) readDescriptor(entry *zipEntry) (uint32, error) {
+	var crc uint32
+	if err := binary.Read(x.r, binary.LittleEndian, &crc); err != nil {
+		return 0, invalidArchive(err)
+	}
+	// The descriptor signature is optional.
+	if crc == zipDataDescriptorSignature {
+		if err := binary.Read(x.r, binary.LittleEndian, &crc); err != nil {
+			return 0, invalidArchive(err)
+		}
+	}
+	sizesLength := 8
+	if entry.zip64 {
+		sizesLength = 16
+	}
+	if _, err := io.CopyN(ioutil.Discard, x.r, int64(sizesLength)); err != nil {
+		return 0, invalidArchive(err)
+	}
+	return crc, nil
+}
+
+// invalidArchive wraps the error of reading a truncated archive.
+func invalidArchive(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of data", ErrInvalidArchive)
+	}
+	return err
+}
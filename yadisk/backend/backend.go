@@ -0,0 +1,134 @@
+// Package backend provides the high-level Disk operations over either
+// the REST API or the WebDAV protocol behind a common interface, so
+// the callers can pick the faster protocol per operation type. Usage:
+//
+//	b := &backend.Router{
+//		Default: backend.NewREST(client),
+//		Routes: map[backend.Op]backend.Backend{
+//			backend.OpUpload:   backend.NewWebDAV("ACCESS_TOKEN"),
+//			backend.OpDownload: backend.NewWebDAV("ACCESS_TOKEN"),
+//		},
+//	}
+//	err := b.Upload(ctx, "/backup/dump.sql", f, true)
+//
+// Paths are absolute paths on Disk, like "/backup/dump.sql". The errors
+// match fs.ErrNotExist, fs.ErrExist and fs.ErrPermission with errors.Is
+// regardless of the backend.
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotDir is returned by List for a file.
+var ErrNotDir = errors.New("backend: not a folder")
+
+// Entry describes a file or folder.
+type Entry struct {
+	// Path to the resource on Disk, without the "disk:" prefix.
+	Path string
+
+	Name     string
+	Dir      bool
+	Size     int64
+	Modified time.Time
+
+	// The MIME type of the file, if known.
+	ContentType string
+
+	// The hex encoded MD5 hash of the file, if known.
+	MD5 string
+}
+
+// Backend is the set of the high-level Disk operations.
+type Backend interface {
+	// Stat returns the description of the file or folder.
+	Stat(ctx context.Context, path string) (*Entry, error)
+
+	// List returns the contents of the folder.
+	List(ctx context.Context, path string) ([]Entry, error)
+
+	// Mkdir creates the folder. The parent folder must exist.
+	Mkdir(ctx context.Context, path string) error
+
+	// Upload stores the data read from r in the file,
+	// replacing the existing one if overwrite is set.
+	Upload(ctx context.Context, path string, r io.Reader, overwrite bool) error
+
+	// Download writes the contents of the file to w.
+	Download(ctx context.Context, path string, w io.Writer) error
+
+	// Copy and Move copy and move the file or folder, replacing
+	// the existing destination if overwrite is set. They return
+	// when the operation is finished.
+	Copy(ctx context.Context, from, to string, overwrite bool) error
+	Move(ctx context.Context, from, to string, overwrite bool) error
+
+	// Delete deletes the file or folder.
+	Delete(ctx context.Context, path string) error
+}
+
+// Op is the type of a Backend operation.
+type Op int
+
+// The operation types.
+const (
+	OpStat Op = iota
+	OpList
+	OpMkdir
+	OpUpload
+	OpDownload
+	OpCopy
+	OpMove
+	OpDelete
+)
+
+// Router is a Backend sending every operation to the backend
+// of its type in Routes, or to Default.
+type Router struct {
+	Default Backend
+	Routes  map[Op]Backend
+}
+
+// backend returns the backend of the operation.
+func (r *Router) backend(op Op) Backend {
+	if b, ok := r.Routes[op]; ok {
+		return b
+	}
+	return r.Default
+}
+
+func (r *Router) Stat(ctx context.Context, path string) (*Entry, error) {
+	return r.backend(OpStat).Stat(ctx, path)
+}
+
+func (r *Router) List(ctx context.Context, path string) ([]Entry, error) {
+	return r.backend(OpList).List(ctx, path)
+}
+
+func (r *Router) Mkdir(ctx context.Context, path string) error {
+	return r.backend(OpMkdir).Mkdir(ctx, path)
+}
+
+func (r *Router) Upload(ctx context.Context, path string, body io.Reader, overwrite bool) error {
+	return r.backend(OpUpload).Upload(ctx, path, body, overwrite)
+}
+
+func (r *Router) Download(ctx context.Context, path string, w io.Writer) error {
+	return r.backend(OpDownload).Download(ctx, path, w)
+}
+
+func (r *Router) Copy(ctx context.Context, from, to string, overwrite bool) error {
+	return r.backend(OpCopy).Copy(ctx, from, to, overwrite)
+}
+
+func (r *Router) Move(ctx context.Context, from, to string, overwrite bool) error {
+	return r.backend(OpMove).Move(ctx, from, to, overwrite)
+}
+
+func (r *Router) Delete(ctx context.Context, path string) error {
+	return r.backend(OpDelete).Delete(ctx, path)
+}
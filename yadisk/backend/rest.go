@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// entryFields are the metainformation fields describing an Entry.
+var entryFields = []string{"path", "name", "type", "size", "modified", "mime_type", "md5"}
+
+// REST is the Backend using the REST API client.
+type REST struct {
+	client *yadisk.Client
+}
+
+// NewREST returns the backend making the requests with the client.
+func NewREST(client *yadisk.Client) *REST {
+	return &REST{client: client}
+}
+
+func (b *REST) Stat(ctx context.Context, path string) (*Entry, error) {
+	resource, _, err := b.client.Resources.Get(ctx, yadisk.Path(path), &yadisk.ResourcesOptions{
+		Fields: entryFields,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restEntry(resource), nil
+}
+
+// List requests the folder contents page by page.
+func (b *REST) List(ctx context.Context, path string) ([]Entry, error) {
+	items, err := b.client.Resources.List(ctx, yadisk.Path(path), &yadisk.ListOptions{
+		Fields: entryFields,
+	})
+	if errors.Is(err, yadisk.ErrNotFolder) {
+		return nil, ErrNotDir
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(items))
+	for i := range items {
+		entries = append(entries, *restEntry(&items[i]))
+	}
+	return entries, nil
+}
+
+func (b *REST) Mkdir(ctx context.Context, path string) error {
+	_, _, err := b.client.Resources.Mkdir(ctx, yadisk.Path(path))
+	return err
+}
+
+func (b *REST) Upload(ctx context.Context, path string, r io.Reader, overwrite bool) error {
+	_, err := b.client.Resources.Upload(ctx, yadisk.Path(path), r, &yadisk.UploadOptions{Overwrite: overwrite})
+	return err
+}
+
+func (b *REST) Download(ctx context.Context, path string, w io.Writer) error {
+	_, err := b.client.Resources.Download(ctx, yadisk.Path(path), w, nil)
+	return err
+}
+
+func (b *REST) Copy(ctx context.Context, from, to string, overwrite bool) error {
+	_, resp, err := b.client.Resources.Copy(ctx, yadisk.Path(from), yadisk.Path(to), &yadisk.CopyOptions{Overwrite: overwrite})
+	if err != nil {
+		return err
+	}
+	return b.client.Resources.WaitOperation(ctx, resp)
+}
+
+func (b *REST) Move(ctx context.Context, from, to string, overwrite bool) error {
+	_, resp, err := b.client.Resources.Move(ctx, yadisk.Path(from), yadisk.Path(to), &yadisk.MoveOptions{Overwrite: overwrite})
+	if err != nil {
+		return err
+	}
+	return b.client.Resources.WaitOperation(ctx, resp)
+}
+
+// Delete moves the file or folder to the Trash.
+func (b *REST) Delete(ctx context.Context, path string) error {
+	resp, err := b.client.Resources.Delete(ctx, yadisk.Path(path), nil)
+	if err != nil {
+		return err
+	}
+	return b.client.Resources.WaitOperation(ctx, resp)
+}
+
+// restEntry describes the resource as an Entry.
+func restEntry(r *yadisk.Resource) *Entry {
+	return &Entry{
+		Path:        r.Path.Rel(),
+		Name:        r.Name,
+		Dir:         r.Type == "dir",
+		Size:        int64(r.Size),
+		Modified:    r.Modified,
+		ContentType: r.MimeType,
+		MD5:         r.MD5,
+	}
+}
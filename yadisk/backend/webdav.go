@@ -0,0 +1,282 @@
+package backend
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultWebDAVURL = "https://webdav.yandex.ru/"
+
+// WebDAVError is the unexpected response status of a WebDAV request.
+type WebDAVError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+func (e *WebDAVError) Error() string {
+	return fmt.Sprintf("Yandex.Disk WebDAV error. %s %s: %d %s",
+		e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is reports whether the target is fs.ErrNotExist for the 404 Not Found
+// and 409 Conflict (missing parent folder) statuses, fs.ErrExist for the
+// 412 Precondition Failed and 405 Method Not Allowed (existing folder)
+// statuses, or fs.ErrPermission for 401 Unauthorized and 403 Forbidden.
+func (e *WebDAVError) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusConflict
+	case fs.ErrExist:
+		return e.StatusCode == http.StatusPreconditionFailed || e.StatusCode == http.StatusMethodNotAllowed
+	case fs.ErrPermission:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// WebDAV is the Backend speaking the Yandex.Disk WebDAV protocol,
+// which is faster than the REST API for some bulk transfers. The
+// uploads and downloads are streamed, the copies and moves are done
+// by the server.
+//
+// Yandex.Disk WebDAV docs: https://yandex.com/dev/disk/webdav/
+type WebDAV struct {
+	// HTTP client used to communicate with the server.
+	HTTPClient *http.Client
+
+	// Base URL of the WebDAV server. Defaults to https://webdav.yandex.ru/.
+	BaseURL *url.URL
+
+	// OAuth access token.
+	AccessToken string
+}
+
+// NewWebDAV returns the WebDAV backend authorized with the OAuth token.
+func NewWebDAV(accessToken string) *WebDAV {
+	baseURL, _ := url.Parse(defaultWebDAVURL)
+	return &WebDAV{
+		HTTPClient:  http.DefaultClient,
+		BaseURL:     baseURL,
+		AccessToken: accessToken,
+	}
+}
+
+// url returns the URL of the path.
+func (b *WebDAV) url(p string) string {
+	u := *b.BaseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + path.Clean("/"+p)
+	return u.String()
+}
+
+// do sends the request and checks the response status is one of the
+// expected ones. The caller must close the response body.
+func (b *WebDAV) do(
+	ctx context.Context,
+	method string,
+	p string,
+	body io.Reader,
+	header http.Header,
+	expected ...int,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.url(p), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", "OAuth "+b.AccessToken)
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return nil, &WebDAVError{Method: method, Path: p, StatusCode: resp.StatusCode}
+}
+
+// propfindBody requests the properties describing an Entry.
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop>
+<D:resourcetype/><D:getcontentlength/><D:getlastmodified/><D:getcontenttype/>
+</D:prop></D:propfind>`
+
+// multistatus is the PROPFIND response.
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength string `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				ContentType   string `xml:"getcontenttype"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// propfind returns the entries of the resource and,
+// with depth 1, of its contents.
+func (b *WebDAV) propfind(ctx context.Context, p string, depth int) ([]Entry, error) {
+	resp, err := b.do(ctx, "PROPFIND", p, strings.NewReader(propfindBody), http.Header{
+		"Depth":        {strconv.Itoa(depth)},
+		"Content-Type": {"application/xml"},
+	}, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		// The href is escaped, and may be an absolute URL.
+		u, err := url.Parse(r.Href)
+		if err != nil {
+			return nil, err
+		}
+		href := path.Clean("/" + strings.TrimPrefix(u.Path, strings.TrimSuffix(b.BaseURL.Path, "/")))
+		entry := Entry{Path: href, Name: path.Base(href)}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			prop := ps.Prop
+			entry.Dir = entry.Dir || prop.ResourceType.Collection != nil
+			if prop.ContentLength != "" {
+				entry.Size, _ = strconv.ParseInt(prop.ContentLength, 10, 64)
+			}
+			if prop.LastModified != "" {
+				entry.Modified, _ = time.Parse(http.TimeFormat, prop.LastModified)
+			}
+			if prop.ContentType != "" {
+				entry.ContentType = prop.ContentType
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (b *WebDAV) Stat(ctx context.Context, p string) (*Entry, error) {
+	entries, err := b.propfind(ctx, p, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, &WebDAVError{Method: "PROPFIND", Path: p, StatusCode: http.StatusNotFound}
+	}
+	return &entries[0], nil
+}
+
+// List lists the folder with a single PROPFIND request.
+func (b *WebDAV) List(ctx context.Context, p string) ([]Entry, error) {
+	entries, err := b.propfind(ctx, p, 1)
+	if err != nil {
+		return nil, err
+	}
+	self := path.Clean("/" + p)
+	contents := make([]Entry, 0, len(entries))
+	isDir := false
+	for _, e := range entries {
+		if e.Path == self {
+			isDir = e.Dir
+			continue
+		}
+		contents = append(contents, e)
+	}
+	if !isDir {
+		return nil, ErrNotDir
+	}
+	return contents, nil
+}
+
+func (b *WebDAV) Mkdir(ctx context.Context, p string) error {
+	resp, err := b.do(ctx, "MKCOL", p, nil, nil, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Upload sends "If-None-Match: *" unless overwrite is set, so the
+// server rejects the upload of an existing file with 412 Precondition
+// Failed, which matches fs.ErrExist.
+func (b *WebDAV) Upload(ctx context.Context, p string, r io.Reader, overwrite bool) error {
+	var header http.Header
+	if !overwrite {
+		header = http.Header{"If-None-Match": {"*"}}
+	}
+	resp, err := b.do(ctx, "PUT", p, r, header, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (b *WebDAV) Download(ctx context.Context, p string, w io.Writer) error {
+	resp, err := b.do(ctx, "GET", p, nil, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (b *WebDAV) Copy(ctx context.Context, from, to string, overwrite bool) error {
+	return b.relocate(ctx, "COPY", from, to, overwrite)
+}
+
+func (b *WebDAV) Move(ctx context.Context, from, to string, overwrite bool) error {
+	return b.relocate(ctx, "MOVE", from, to, overwrite)
+}
+
+// relocate sends the COPY or MOVE request.
+func (b *WebDAV) relocate(ctx context.Context, method, from, to string, overwrite bool) error {
+	header := http.Header{
+		"Destination": {b.url(to)},
+		"Overwrite":   {"F"},
+	}
+	if overwrite {
+		header.Set("Overwrite", "T")
+	}
+	resp, err := b.do(ctx, method, from, nil, header, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (b *WebDAV) Delete(ctx context.Context, p string) error {
+	resp, err := b.do(ctx, "DELETE", p, nil, nil, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
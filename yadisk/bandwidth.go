@@ -0,0 +1,192 @@
+package yadisk
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxThrottledRead is the largest read done at once by a throttled
+// reader, so the data flows smoothly instead of in bursts.
+const maxThrottledRead = 32 << 10
+
+// BandwidthWindow is a time-of-day period with its own bandwidth limit.
+type BandwidthWindow struct {
+	// Start and end of the window as offsets from the local midnight.
+	// If End is before Start, the window spans midnight.
+	Start time.Duration
+	End   time.Duration
+
+	// The limit in bytes per second and the burst size in bytes
+	// within the window. Zero Rate means no limit.
+	Rate  int64
+	Burst int64
+}
+
+// contains reports whether the window contains the time of day of t.
+func (w BandwidthWindow) contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// BandwidthSchedule is a list of time-of-day windows.
+// The first window containing the current time defines the limit.
+type BandwidthSchedule []BandwidthWindow
+
+// At returns the window containing the time of day of t.
+func (s BandwidthSchedule) At(t time.Time) (BandwidthWindow, bool) {
+	for _, w := range s {
+		if w.contains(t) {
+			return w, true
+		}
+	}
+	return BandwidthWindow{}, false
+}
+
+// BandwidthLimiter limits the transfer rate with a token bucket.
+// A single limiter can be shared by many transfers, which then split
+// the bandwidth between them. It is safe for concurrent use, and the
+// limit can be changed while the transfers are running.
+//
+// Set it as the Client Bandwidth to limit all transfers of the client,
+// or in the transfer options to limit a single transfer.
+type BandwidthLimiter struct {
+	mu       sync.Mutex
+	rate     int64
+	burst    int64
+	schedule BandwidthSchedule
+	tokens   float64
+	last     time.Time
+}
+
+// NewBandwidthLimiter returns a limiter allowing rate bytes per second
+// with bursts of up to burst bytes. Zero burst means a second worth
+// of data, zero rate means no limit.
+func NewBandwidthLimiter(rate, burst int64) *BandwidthLimiter {
+	l := new(BandwidthLimiter)
+	l.SetLimit(rate, burst)
+	return l
+}
+
+// SetLimit changes the limit outside of the scheduled windows.
+func (l *BandwidthLimiter) SetLimit(rate, burst int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+	l.burst = burst
+}
+
+// Limit returns the limit outside of the scheduled windows.
+func (l *BandwidthLimiter) Limit() (rate, burst int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate, l.burst
+}
+
+// SetSchedule sets the time-of-day windows with their own limits.
+// Outside of the windows the limit set by SetLimit applies.
+func (l *BandwidthLimiter) SetSchedule(schedule BandwidthSchedule) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.schedule = schedule
+}
+
+// current returns the limit in effect at the time.
+// It must be called with the mutex held.
+func (l *BandwidthLimiter) current(now time.Time) (rate, burst int64) {
+	rate, burst = l.rate, l.burst
+	if w, ok := l.schedule.At(now); ok {
+		rate, burst = w.Rate, w.Burst
+	}
+	if burst <= 0 {
+		burst = rate
+	}
+	return rate, burst
+}
+
+// WaitN blocks until n bytes can be transferred
+// or the context is done.
+func (l *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	for n > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		rate, burst := l.current(now)
+		if rate <= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+
+		// Refill the bucket.
+		if !l.last.IsZero() {
+			l.tokens += now.Sub(l.last).Seconds() * float64(rate)
+		} else {
+			l.tokens = float64(burst)
+		}
+		l.tokens = math.Min(l.tokens, float64(burst))
+		l.last = now
+
+		take := n
+		if int64(take) > burst {
+			take = int(burst)
+		}
+		if l.tokens >= float64(take) {
+			l.tokens -= float64(take)
+			n -= take
+			l.mu.Unlock()
+			continue
+		}
+		wait := time.Duration((float64(take) - l.tokens) / float64(rate) * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// throttledReader is a reader limited by one or more limiters.
+type throttledReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*BandwidthLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > maxThrottledRead {
+		p = p[:maxThrottledRead]
+	}
+	n, err := r.r.Read(p)
+	for _, l := range r.limiters {
+		if waitErr := l.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// throttle limits reading from r by the client bandwidth limiter
+// and the transfer one, if they are set.
+func (c *Client) throttle(ctx context.Context, r io.Reader, transfer *BandwidthLimiter) io.Reader {
+	var limiters []*BandwidthLimiter
+	if c.Bandwidth != nil {
+		limiters = append(limiters, c.Bandwidth)
+	}
+	if transfer != nil && transfer != c.Bandwidth {
+		limiters = append(limiters, transfer)
+	}
+	if len(limiters) == 0 {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, limiters: limiters}
+}
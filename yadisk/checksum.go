@@ -0,0 +1,128 @@
+package yadisk
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strings"
+)
+
+// VerifyMode controls the checksum verification of transfers.
+type VerifyMode int
+
+const (
+	// VerifyAuto verifies the file-to-file transfers made
+	// with UploadFile and DownloadFile only.
+	VerifyAuto VerifyMode = iota
+
+	// VerifyAlways verifies all transfers.
+	VerifyAlways
+
+	// VerifyNever turns the verification off.
+	VerifyNever
+)
+
+// enabled reports whether the transfer should be verified.
+func (m VerifyMode) enabled(fileToFile bool) bool {
+	return m == VerifyAlways || m == VerifyAuto && fileToFile
+}
+
+// checksums computes the MD5 and SHA-256 hashes of a file
+// which is read sequentially, possibly with repeated parts.
+type checksums struct {
+	md5    hash.Hash
+	sha256 hash.Hash
+	offset int64
+}
+
+func newChecksums() *checksums {
+	return &checksums{md5: md5.New(), sha256: sha256.New()}
+}
+
+// Write hashes the next part of the file.
+func (c *checksums) Write(p []byte) (int, error) {
+	c.md5.Write(p)
+	c.sha256.Write(p)
+	c.offset += int64(len(p))
+	return len(p), nil
+}
+
+// writeAt hashes the part of p at the offset off which hasn't been
+// hashed yet. Parts which are not adjacent to the hashed ones are ignored.
+func (c *checksums) writeAt(p []byte, off int64) {
+	if off > c.offset || off+int64(len(p)) <= c.offset {
+		return
+	}
+	c.Write(p[c.offset-off:])
+}
+
+// readFrom hashes the rest of the file read from src of the size.
+func (c *checksums) readFrom(src io.ReaderAt, size int64) error {
+	if c.offset >= size {
+		return nil
+	}
+	_, err := io.Copy(c, io.NewSectionReader(src, c.offset, size-c.offset))
+	return err
+}
+
+// hex returns the hex encoded MD5 and SHA-256 hashes.
+func (c *checksums) hex() (md5Hash, sha256Hash string) {
+	return hex.EncodeToString(c.md5.Sum(nil)), hex.EncodeToString(c.sha256.Sum(nil))
+}
+
+// verify compares the hashes with the ones of the resource.
+// Hashes missing in the resource are not compared.
+func (c *checksums) verify(path Path, resource *Resource) error {
+	expected := []struct {
+		algorithm string
+		remote    string
+		local     hash.Hash
+	}{
+		{"md5", resource.MD5, c.md5},
+		{"sha256", resource.SHA256, c.sha256},
+	}
+	for _, e := range expected {
+		if e.remote == "" {
+			continue
+		}
+		local := hex.EncodeToString(e.local.Sum(nil))
+		if !strings.EqualFold(local, e.remote) {
+			return &ChecksumError{
+				Path:      path,
+				Algorithm: e.algorithm,
+				Remote:    e.remote,
+				Local:     local,
+			}
+		}
+	}
+	return nil
+}
+
+// checksumReader hashes the data read from the underlying reader.
+type checksumReader struct {
+	r         io.Reader
+	checksums *checksums
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.checksums.Write(p[:n])
+	return n, err
+}
+
+// checksumSectionReader hashes the data read from a section
+// of the file starting at the offset.
+type checksumSectionReader struct {
+	r         io.Reader
+	offset    int64
+	checksums *checksums
+}
+
+func (r *checksumSectionReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.checksums.writeAt(p[:n], r.offset)
+	r.offset += int64(n)
+	return n, err
+}
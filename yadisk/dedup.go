@@ -0,0 +1,199 @@
+package yadisk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// UploadStatus tells how a file was uploaded by UploadFileDedup.
+type UploadStatus int
+
+const (
+	// UploadTransferred means the file data has been sent.
+	UploadTransferred UploadStatus = iota
+
+	// UploadDeduplicated means the file has been created from
+	// the data already stored by Yandex.Disk, without sending it.
+	UploadDeduplicated
+
+	// UploadSkipped means the same file already exists at the path.
+	UploadSkipped
+)
+
+func (s UploadStatus) String() string {
+	switch s {
+	case UploadTransferred:
+		return "transferred"
+	case UploadDeduplicated:
+		return "deduplicated"
+	case UploadSkipped:
+		return "skipped"
+	}
+	return "UploadStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
+// UploadResult is the result of UploadFileDedup.
+type UploadResult struct {
+	Status UploadStatus
+
+	// The response of the last request made for the upload.
+	Response *Response
+}
+
+// DedupUploadOptions specifies the optional parameters to the
+// ResourcesService.UploadFileDedup method.
+type DedupUploadOptions struct {
+	UploadOptions
+
+	// Try the hash-based instant upload before sending the data.
+	// The uploader is sent an empty request with the Etag, Sha256
+	// and Size headers, and creates the file without the data
+	// if it already stores the data with these hashes.
+	InstantUpload bool `url:"-"`
+}
+
+// UploadFileDedup uploads the local file to the path like UploadFile,
+// but avoids sending the data when possible. The upload is skipped
+// if a file with the same size, MD5 and SHA-256 hashes already
+// exists at the path. With the InstantUpload option the hash-based
+// instant upload is tried before sending the data. The size and the
+// hashes of the instantly uploaded file are always checked, whatever
+// the Verify option is, and on mismatch the data is sent replacing it.
+func (s *ResourcesService) UploadFileDedup(
+	ctx context.Context,
+	localPath string,
+	path Path,
+	opt *DedupUploadOptions,
+) (*UploadResult, error) {
+	if opt == nil {
+		opt = new(DedupUploadOptions)
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := newChecksums()
+	size, err := io.Copy(sums, f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip the file if the remote one is the same.
+	resource, resp, err := s.Get(ctx, path, &ResourcesOptions{
+		Fields: []string{"path", "type", "size", "md5", "sha256"},
+	})
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		if resource.Type == "file" && int64(resource.Size) == size &&
+			resource.MD5 != "" && sums.verify(path, resource) == nil {
+			return &UploadResult{Status: UploadSkipped, Response: resp}, nil
+		}
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+	default:
+		return nil, err
+	}
+
+	uploadOpt := opt.UploadOptions
+	if opt.InstantUpload {
+		resp, ok, err := s.instantUpload(ctx, path, sums, size, &opt.UploadOptions)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			// The uploader may accept the request without creating
+			// the file from the stored data, like an empty upload.
+			ok, err = s.instantUploaded(ctx, path, sums, size)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				// Replace the wrong file with the data.
+				uploadOpt.Overwrite = true
+			}
+		}
+		if ok {
+			return &UploadResult{Status: UploadDeduplicated, Response: resp}, nil
+		}
+	}
+
+	resp, err = s.uploadResumable(
+		ctx,
+		path,
+		f,
+		size,
+		&ResumableUploadOptions{UploadOptions: uploadOpt},
+		true,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{Status: UploadTransferred, Response: resp}, nil
+}
+
+// instantUpload tries to create the file from the data with the same
+// hashes stored by Yandex.Disk. It reports whether the file is created.
+// The uploader errors other than the unknown hash are returned.
+func (s *ResourcesService) instantUpload(
+	ctx context.Context,
+	path Path,
+	sums *checksums,
+	size int64,
+	opt *UploadOptions,
+) (*Response, bool, error) {
+	link, resp, err := s.GetUploadLink(ctx, path, opt)
+	if err != nil {
+		return resp, false, err
+	}
+
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.instant_upload"),
+		uploadMethod(link),
+		link.Href,
+		nil,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	md5Hash, sha256Hash := sums.hex()
+	req.Header.Set("Etag", md5Hash)
+	req.Header.Set("Sha256", sha256Hash)
+	req.Header.Set("Size", strconv.FormatInt(size, 10))
+
+	resp, err = s.client.Do(req, nil)
+	if err != nil {
+		// The uploader doesn't have the data, it must be sent.
+		var apiErr *APIError
+		if errors.As(err, &apiErr) &&
+			(apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusPreconditionFailed) {
+			return resp, false, nil
+		}
+		return resp, false, err
+	}
+	return resp, resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK, nil
+}
+
+// instantUploaded reports whether the file created by the instant
+// upload has the size and the hashes of the local file.
+func (s *ResourcesService) instantUploaded(
+	ctx context.Context,
+	path Path,
+	sums *checksums,
+	size int64,
+) (bool, error) {
+	resource, _, err := s.Get(ctx, path, &ResourcesOptions{
+		Fields: []string{"path", "size", "md5", "sha256"},
+	})
+	if err != nil {
+		return false, err
+	}
+	return int64(resource.Size) == size && resource.MD5 != "" &&
+		sums.verify(path, resource) == nil, nil
+}
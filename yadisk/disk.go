@@ -1,9 +1,6 @@
 package yadisk
 
-import (
-	"context"
-	"net/http"
-)
+import "context"
 
 // SystemFolders is the absolute addresses of Disk system folders.
 type SystemFolders struct {
@@ -46,15 +43,15 @@ type DiskService service
 // Get returns general information about
 // a user's Disk: the available space, addresses of system folders, and so on.
 // https://tech.yandex.com/disk/api/reference/capacity-docpage/
-func (s *DiskService) Get(ctx context.Context) (*Disk, *http.Response, error) {
-	url := "disk"
-	req, err := s.client.NewRequest("GET", url, nil)
+func (s *DiskService) Get(ctx context.Context) (*Disk, *Response, error) {
+	url := "disk/"
+	req, err := s.client.NewRequestWithContext(WithOperation(ctx, "disk.get"), "GET", url, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	disk := new(Disk)
-	resp, err := s.client.Do(ctx, req, disk)
+	resp, err := s.client.Do(req, disk)
 	if err != nil {
 		return nil, resp, err
 	}
@@ -0,0 +1,278 @@
+// Package diskfs adapts a Yandex.Disk folder to the io/fs interfaces,
+// so it can be used with html/template, http.FS, fs.WalkDir, fs.Glob
+// and the like. Usage:
+//
+//	fsys := diskfs.New(client, "/site")
+//	http.Handle("/", http.FileServer(http.FS(fsys)))
+//
+// Names are slash-separated paths relative to the root folder,
+// as required by io/fs. API errors can be checked with errors.Is
+// against fs.ErrNotExist and fs.ErrPermission.
+package diskfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
+
+// resourceFields are the metainformation fields requested
+// for the files and folders.
+var resourceFields = []string{"name", "path", "type", "size", "modified", "md5", "sha256", "revision", "mime_type"}
+
+// FS is a read-only file system rooted at a Disk folder. It implements
+// fs.FS, fs.ReadDirFS, fs.StatFS and fs.ReadFileFS.
+type FS struct {
+	client *yadisk.Client
+	root   yadisk.Path
+	ctx    context.Context
+}
+
+// New returns a file system rooted at the folder at the path,
+// like "/site" or "app:/". The requests are made with the background
+// context, see WithContext.
+func New(client *yadisk.Client, root string) *FS {
+	return &FS{client: client, root: yadisk.Path(root), ctx: context.Background()}
+}
+
+// WithContext returns a copy of the file system making
+// the requests with ctx.
+func (fsys *FS) WithContext(ctx context.Context) *FS {
+	copied := *fsys
+	copied.ctx = ctx
+	return &copied
+}
+
+// remotePath returns the Disk path of the name,
+// or an error if the name is invalid.
+func (fsys *FS) remotePath(op, name string) (yadisk.Path, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return fsys.root, nil
+	}
+	return fsys.root.Join(name), nil
+}
+
+// Open opens the file or folder. The file contents are requested
+// on the first read, see yadisk.File.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	resource, err := fsys.get("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info := &fileInfo{resource: resource, name: path.Base(name)}
+	if resource.Type == "dir" {
+		return &dir{fsys: fsys, name: name, info: info}, nil
+	}
+	return &file{fsys: fsys, name: name, info: info}, nil
+}
+
+// Stat returns the file info of the file or folder.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	resource, err := fsys.get("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{resource: resource, name: path.Base(name)}, nil
+}
+
+// ReadDir returns the folder entries sorted by name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	remotePath, err := fsys.remotePath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fsys.list(remotePath)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// ReadFile returns the contents of the file.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	resource, err := fsys.get("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	if resource.Type == "dir" {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: errIsDir}
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, resource.Size))
+	if _, err := fsys.client.Resources.Download(fsys.ctx, resource.Path, buf, nil); err != nil {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: err}
+	}
+	return buf.Bytes(), nil
+}
+
+// get returns the metainformation of the file or folder.
+func (fsys *FS) get(op, name string) (*yadisk.Resource, error) {
+	remotePath, err := fsys.remotePath(op, name)
+	if err != nil {
+		return nil, err
+	}
+	resource, _, err := fsys.client.Resources.Get(fsys.ctx, remotePath, &yadisk.ResourcesOptions{
+		Fields: resourceFields,
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return resource, nil
+}
+
+// list returns the entries of the folder sorted by name.
+func (fsys *FS) list(remotePath yadisk.Path) ([]fs.DirEntry, error) {
+	items, err := fsys.client.Resources.List(fsys.ctx, remotePath, &yadisk.ListOptions{
+		Fields: resourceFields,
+	})
+	if errors.Is(err, yadisk.ErrNotFolder) {
+		return nil, errNotDir
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(items))
+	for i := range items {
+		item := &items[i]
+		entries = append(entries, fs.FileInfoToDirEntry(&fileInfo{resource: item, name: item.Name}))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// fileInfo describes a resource as fs.FileInfo.
+type fileInfo struct {
+	resource *yadisk.Resource
+	name     string
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return int64(fi.resource.Size) }
+func (fi *fileInfo) ModTime() time.Time { return fi.resource.Modified }
+func (fi *fileInfo) IsDir() bool        { return fi.resource.Type == "dir" }
+
+// Sys returns the *yadisk.Resource.
+func (fi *fileInfo) Sys() interface{} { return fi.resource }
+
+// Mode returns the read-only permissions, since Disk has none.
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// file is an opened file. The remote file is opened on the first read.
+type file struct {
+	fsys   *FS
+	name   string
+	info   *fileInfo
+	remote *yadisk.File
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *file) open() error {
+	if f.remote != nil {
+		return nil
+	}
+	remote, err := f.fsys.client.Resources.Open(f.fsys.ctx, f.info.resource.Path)
+	if err != nil {
+		return &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	f.remote = remote
+	return nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.info.Size() == 0 {
+		return 0, io.EOF
+	}
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.remote.Read(p)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.remote.ReadAt(p, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if err := f.open(); err != nil {
+		return 0, err
+	}
+	return f.remote.Seek(offset, whence)
+}
+
+func (f *file) Close() error {
+	if f.remote == nil {
+		return nil
+	}
+	return f.remote.Close()
+}
+
+// dir is an opened folder. The entries are listed on the first read.
+type dir struct {
+	fsys    *FS
+	name    string
+	info    *fileInfo
+	entries []fs.DirEntry
+	listed  bool
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errIsDir}
+}
+
+func (d *dir) Close() error { return nil }
+
+// ReadDir returns the next n entries, or all the remaining
+// entries if n <= 0, like fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		entries, err := d.fsys.list(d.info.resource.Path)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: err}
+		}
+		d.entries, d.listed = entries, true
+	}
+
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,438 @@
+package diskfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// ErrUnsupported is returned for the operations Disk can't perform,
+// like appending to a file, writing at an offset, reading a file
+// opened for writing or changing the permissions. It is
+// errors.ErrUnsupported, so both can be checked with errors.Is.
+var ErrUnsupported = errors.ErrUnsupported
+
+var (
+	errDirNotEmpty  = errors.New("directory not empty")
+	errNotWritable  = errors.New("file not opened for writing")
+	errDestIsFolder = errors.New("destination is a folder")
+)
+
+// File is a file opened by WriteFS. Its method set is the one
+// of *os.File and afero.File, so the code written against them
+// needs a thin adapter at most.
+type File interface {
+	fs.File
+	io.Writer
+	io.WriterAt
+	io.ReaderAt
+	io.Seeker
+	io.StringWriter
+	Name() string
+	ReadDir(n int) ([]fs.DirEntry, error)
+	Readdir(count int) ([]fs.FileInfo, error)
+	Readdirnames(n int) ([]string, error)
+	Sync() error
+	Truncate(size int64) error
+}
+
+// WriteFS is a writable file system rooted at a Disk folder. Its
+// methods mirror the os package and afero.Fs, so it can share an
+// interface with the local disk and other storages. Usage:
+//
+//	wfs := diskfs.NewWriteFS(client, "/backups")
+//	f, err := wfs.Create("2020/05/db.sql")
+//	...
+//	io.Copy(f, dump)
+//	err = f.Close()
+//
+// Names are slash-separated paths relative to the root folder, a
+// leading slash is allowed and ".." elements can't leave the root.
+//
+// Disk stores whole files only, so the files are written sequentially
+// from the start. The data is streamed to Disk while being written,
+// and the file appears on Disk when Close succeeds. Appending,
+// writing at an offset other than the current size, truncating,
+// reading a file opened for writing and changing the permissions,
+// owner or times return ErrUnsupported. Opening an existing file
+// for writing requires os.O_TRUNC.
+type WriteFS struct {
+	fsys *FS
+
+	// Permanently makes Remove and RemoveAll delete the files
+	// and folders without moving them to the Trash.
+	Permanently bool
+}
+
+// NewWriteFS returns a writable file system rooted at the folder at
+// the path, like "/backups" or "app:/". The requests are made with
+// the background context, see WithContext.
+func NewWriteFS(client *yadisk.Client, root string) *WriteFS {
+	return &WriteFS{fsys: New(client, root)}
+}
+
+// WithContext returns a copy of the file system making
+// the requests with ctx.
+func (w *WriteFS) WithContext(ctx context.Context) *WriteFS {
+	copied := *w
+	copied.fsys = w.fsys.WithContext(ctx)
+	return &copied
+}
+
+// Name returns the name of the file system.
+func (w *WriteFS) Name() string { return "yadisk" }
+
+// cleanName returns the io/fs name of the name.
+func cleanName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// Create creates or truncates the file, like os.Create.
+func (w *WriteFS) Create(name string) (File, error) {
+	return w.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// Open opens the file or folder for reading, like os.Open.
+func (w *WriteFS) Open(name string) (File, error) {
+	return w.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the file with the flag, like os.OpenFile. The
+// permissions are ignored. See WriteFS for the supported flags.
+func (w *WriteFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		f, err := w.fsys.Open(cleanName(name))
+		if err != nil {
+			return nil, err
+		}
+		return &readFile{File: f, name: name}, nil
+	}
+	if flag&os.O_APPEND != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrUnsupported}
+	}
+
+	resource, err := w.fsys.get("open", cleanName(name))
+	switch {
+	case err == nil && resource.Type == "dir":
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	case err == nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case err == nil && flag&os.O_TRUNC == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrUnsupported}
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+	case err != nil:
+		return nil, err
+	}
+
+	remotePath, _ := w.fsys.remotePath("open", cleanName(name))
+	writer, err := w.fsys.client.Resources.Create(w.fsys.ctx, remotePath, &yadisk.UploadOptions{
+		Overwrite: flag&os.O_EXCL == 0,
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &writeFile{name: name, remotePath: remotePath, w: writer}, nil
+}
+
+// Mkdir creates the folder, like os.Mkdir. The permissions are ignored.
+func (w *WriteFS) Mkdir(name string, perm fs.FileMode) error {
+	remotePath, err := w.fsys.remotePath("mkdir", cleanName(name))
+	if err != nil {
+		return err
+	}
+	if _, _, err := w.fsys.client.Resources.Mkdir(w.fsys.ctx, remotePath); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// MkdirAll creates the folder along with the missing parents,
+// like os.MkdirAll. The permissions are ignored.
+func (w *WriteFS) MkdirAll(name string, perm fs.FileMode) error {
+	remotePath, err := w.fsys.remotePath("mkdir", cleanName(name))
+	if err != nil {
+		return err
+	}
+	if err := w.fsys.client.Resources.MkdirAll(w.fsys.ctx, remotePath); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Remove removes the file or the empty folder, like os.Remove.
+func (w *WriteFS) Remove(name string) error {
+	resource, err := w.fsys.get("remove", cleanName(name))
+	if err != nil {
+		return err
+	}
+	if resource.Type == "dir" {
+		entries, err := w.fsys.list(resource.Path)
+		if err != nil {
+			return &fs.PathError{Op: "remove", Path: name, Err: err}
+		}
+		if len(entries) > 0 {
+			return &fs.PathError{Op: "remove", Path: name, Err: errDirNotEmpty}
+		}
+	}
+	return w.delete(name, resource.Path)
+}
+
+// RemoveAll removes the file or the folder with its contents,
+// like os.RemoveAll. A missing file is not an error.
+func (w *WriteFS) RemoveAll(name string) error {
+	remotePath, err := w.fsys.remotePath("remove", cleanName(name))
+	if err != nil {
+		return err
+	}
+	if err := w.delete(name, remotePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// delete deletes the resource and waits for the deletion to finish.
+func (w *WriteFS) delete(name string, remotePath yadisk.Path) error {
+	resources := w.fsys.client.Resources
+	resp, err := resources.Delete(w.fsys.ctx, remotePath, &yadisk.DeleteOptions{
+		Permanently: w.Permanently,
+	})
+	if err == nil {
+		err = resources.WaitOperation(w.fsys.ctx, resp)
+	}
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Rename moves the file or folder, like os.Rename. An existing file
+// at newname is replaced, an existing folder is an error.
+func (w *WriteFS) Rename(oldname, newname string) error {
+	linkErr := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	from, err := w.fsys.remotePath("rename", cleanName(oldname))
+	if err != nil {
+		return linkErr(fs.ErrInvalid)
+	}
+	to, err := w.fsys.remotePath("rename", cleanName(newname))
+	if err != nil {
+		return linkErr(fs.ErrInvalid)
+	}
+	if info, err := w.fsys.Stat(cleanName(newname)); err == nil && info.IsDir() {
+		return linkErr(errDestIsFolder)
+	}
+
+	resources := w.fsys.client.Resources
+	_, resp, err := resources.Move(w.fsys.ctx, from, to, &yadisk.MoveOptions{Overwrite: true})
+	if err == nil {
+		err = resources.WaitOperation(w.fsys.ctx, resp)
+	}
+	if err != nil {
+		return linkErr(err)
+	}
+	return nil
+}
+
+// Stat returns the file info of the file or folder, like os.Stat.
+// Sys of the file info returns the *yadisk.Resource.
+func (w *WriteFS) Stat(name string) (fs.FileInfo, error) {
+	return w.fsys.Stat(cleanName(name))
+}
+
+// Chmod returns ErrUnsupported, Disk has no permissions.
+func (w *WriteFS) Chmod(name string, mode fs.FileMode) error {
+	return &fs.PathError{Op: "chmod", Path: name, Err: ErrUnsupported}
+}
+
+// Chown returns ErrUnsupported, Disk has no owners.
+func (w *WriteFS) Chown(name string, uid, gid int) error {
+	return &fs.PathError{Op: "chown", Path: name, Err: ErrUnsupported}
+}
+
+// Chtimes returns ErrUnsupported, the times are set by Disk.
+func (w *WriteFS) Chtimes(name string, atime, mtime time.Time) error {
+	return &fs.PathError{Op: "chtimes", Path: name, Err: ErrUnsupported}
+}
+
+// readFile is a file or folder opened for reading.
+type readFile struct {
+	fs.File
+	name string
+}
+
+func (f *readFile) Name() string { return f.name }
+
+func (f *readFile) ReadAt(p []byte, off int64) (int, error) {
+	if r, ok := f.File.(io.ReaderAt); ok {
+		return r.ReadAt(p, off)
+	}
+	return 0, &fs.PathError{Op: "read", Path: f.name, Err: errIsDir}
+}
+
+func (f *readFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, &fs.PathError{Op: "seek", Path: f.name, Err: errIsDir}
+}
+
+func (f *readFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d, ok := f.File.(fs.ReadDirFile); ok {
+		return d.ReadDir(n)
+	}
+	return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errNotDir}
+}
+
+func (f *readFile) Readdir(count int) ([]fs.FileInfo, error) {
+	entries, err := f.ReadDir(count)
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, _ := e.Info()
+		infos = append(infos, info)
+	}
+	return infos, err
+}
+
+func (f *readFile) Readdirnames(n int) ([]string, error) {
+	entries, err := f.ReadDir(n)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names, err
+}
+
+func (f *readFile) Write(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: errNotWritable}
+}
+
+func (f *readFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: errNotWritable}
+}
+
+func (f *readFile) WriteString(s string) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: f.name, Err: errNotWritable}
+}
+
+func (f *readFile) Truncate(size int64) error {
+	return &fs.PathError{Op: "truncate", Path: f.name, Err: errNotWritable}
+}
+
+func (f *readFile) Sync() error { return nil }
+
+// writeFile is a file opened for writing. The data is streamed
+// to the writer returned by yadisk.ResourcesService.Create.
+type writeFile struct {
+	name       string
+	remotePath yadisk.Path
+	w          io.WriteCloser
+	size       int64
+	closed     bool
+}
+
+func (f *writeFile) Name() string { return f.name }
+
+// Stat returns the file info with the size written so far.
+func (f *writeFile) Stat() (fs.FileInfo, error) {
+	return &fileInfo{
+		resource: &yadisk.Resource{
+			Name: f.remotePath.Base(),
+			Path: f.remotePath,
+			Type: "file",
+			Size: uint(f.size),
+		},
+		name: path.Base(f.name),
+	}, nil
+}
+
+func (f *writeFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrClosed}
+	}
+	n, err := f.w.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, &fs.PathError{Op: "write", Path: f.name, Err: err}
+	}
+	return n, nil
+}
+
+func (f *writeFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// WriteAt writes at the current size only,
+// other offsets return ErrUnsupported.
+func (f *writeFile) WriteAt(p []byte, off int64) (int, error) {
+	if off != f.size {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: ErrUnsupported}
+	}
+	return f.Write(p)
+}
+
+// Seek reports the current size, seeking elsewhere returns ErrUnsupported.
+func (f *writeFile) Seek(offset int64, whence int) (int64, error) {
+	switch {
+	case whence == io.SeekStart && offset == f.size,
+		whence == io.SeekCurrent && offset == 0,
+		whence == io.SeekEnd && offset == 0:
+		return f.size, nil
+	}
+	return 0, &fs.PathError{Op: "seek", Path: f.name, Err: ErrUnsupported}
+}
+
+// Truncate to the current size is a no-op,
+// other sizes return ErrUnsupported.
+func (f *writeFile) Truncate(size int64) error {
+	if size != f.size {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: ErrUnsupported}
+	}
+	return nil
+}
+
+func (f *writeFile) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: f.name, Err: ErrUnsupported}
+}
+
+func (f *writeFile) ReadAt(p []byte, off int64) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: f.name, Err: ErrUnsupported}
+}
+
+func (f *writeFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errNotDir}
+}
+
+func (f *writeFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errNotDir}
+}
+
+func (f *writeFile) Readdirnames(n int) ([]string, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errNotDir}
+}
+
+// Sync is a no-op, the data is committed by Close.
+func (f *writeFile) Sync() error { return nil }
+
+// Close finishes the upload and returns its error.
+func (f *writeFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if err := f.w.Close(); err != nil {
+		return &fs.PathError{Op: "close", Path: f.name, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,666 @@
+package yadisk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// Defaults for the DownloadTo options.
+	defaultDownloadChunkSize   = 8 << 20
+	defaultDownloadConcurrency = 4
+	defaultDownloadRetries     = 5
+)
+
+// errRangesNotSupported is returned for a ranged request
+// if the server sends the whole file.
+var errRangesNotSupported = errors.New("yadisk: range requests are not supported")
+
+// DownloadOptions specifies the optional parameters to the
+// ResourcesService.Download and ResourcesService.DownloadTo methods.
+// Download uses only the progress options.
+type DownloadOptions struct {
+	// The number of bytes requested with a single range request.
+	// Defaults to 8 MiB.
+	ChunkSize int64
+
+	// The number of chunks downloaded in parallel. Defaults to 4.
+	Concurrency int
+
+	// The number of times a failed chunk is retried. Defaults to 5.
+	MaxRetries int
+
+	// Path to the local file where the download state is kept.
+	// If set, the downloaded chunks are recorded there, and a later
+	// call with the same destination skips them. The file is removed
+	// after a successful download.
+	StatePath string
+
+	// Progress, if set, is called with the download progress.
+	Progress ProgressFunc
+
+	// The minimal time between progress reports. Defaults to 500ms.
+	ProgressInterval time.Duration
+
+	// Bandwidth, if set, limits the download rate in addition
+	// to the Client Bandwidth.
+	Bandwidth *BandwidthLimiter
+
+	// Verify controls whether the MD5 and SHA-256 hashes of the
+	// downloaded data are compared to the ones of the resource.
+	// On mismatch a *ChecksumError is returned. DownloadTo reads
+	// the data back from the destination, which must implement
+	// io.ReaderAt for the verification.
+	Verify VerifyMode
+
+	// Delete the downloaded local file if the verification fails.
+	// Only DownloadFile can delete the downloaded data.
+	DeleteOnMismatch bool
+}
+
+// downloadState is the sidecar state of a partial download.
+type downloadState struct {
+	Path      Path  `json:"path"`
+	Revision  uint  `json:"revision"`
+	Size      int64 `json:"size"`
+	ChunkSize int64 `json:"chunk_size"`
+	Done      []int `json:"done"`
+}
+
+// GetDownloadLink requests the URL for downloading the file at the path.
+// For a folder the link points to the zip archive of its contents.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/content-docpage/
+func (s *ResourcesService) GetDownloadLink(ctx context.Context, path Path) (*Link, *Response, error) {
+	params := url.Values{"path": {string(path)}}
+
+	url := "disk/resources/download"
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.download_link"),
+		"GET",
+		url,
+		nil,
+		WithQuery(params),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link := new(Link)
+	resp, err := s.client.Do(req, link)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return link, resp, nil
+}
+
+// Download writes the contents of the file at the path to w
+// in a single stream.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/content-docpage/
+func (s *ResourcesService) Download(
+	ctx context.Context,
+	path Path,
+	w io.Writer,
+	opt *DownloadOptions,
+) (*Response, error) {
+	if opt == nil {
+		opt = new(DownloadOptions)
+	}
+
+	link, resp, err := s.GetDownloadLink(ctx, path)
+	if err != nil {
+		return resp, err
+	}
+
+	var resource *Resource
+	if opt.Verify.enabled(false) {
+		resource, resp, err = s.Get(ctx, path, &ResourcesOptions{
+			Fields: []string{"path", "md5", "sha256"},
+		})
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	ctx = WithOperation(ctx, "resources.download")
+	req, err := s.client.NewRequestWithContext(ctx, "GET", link.Href, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = s.client.stream(req)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+
+	tracker := newProgressTracker(opt.Progress, opt.ProgressInterval, resp.ContentLength)
+	defer tracker.finish()
+
+	var sums *checksums
+	if resource != nil {
+		sums = newChecksums()
+		w = io.MultiWriter(w, sums)
+	}
+
+	pw := &progressWriter{w: w, tracker: tracker}
+	_, err = io.Copy(pw, s.client.throttle(ctx, resp.Body, opt.Bandwidth))
+	s.client.observe(Event{
+		Kind:       EventBytesDownloaded,
+		Operation:  operationFromContext(ctx),
+		StatusCode: resp.StatusCode,
+		Bytes:      pw.written,
+	})
+	if err != nil || sums == nil {
+		return resp, err
+	}
+	return resp, sums.verify(path, resource)
+}
+
+// DownloadTo downloads the file at the path into dst using parallel
+// HTTP range requests. If the server doesn't support ranges, the file
+// is downloaded in a single stream. Failed chunks are retried, an
+// expired download link is requested again, and with the StatePath
+// option an interrupted download can be resumed later.
+// The size of the downloaded data is verified against the size
+// of the resource, which is returned on success.
+func (s *ResourcesService) DownloadTo(
+	ctx context.Context,
+	path Path,
+	dst io.WriterAt,
+	opt *DownloadOptions,
+) (*Resource, error) {
+	if opt == nil {
+		opt = new(DownloadOptions)
+	}
+	return s.downloadTo(ctx, path, dst, opt, false)
+}
+
+// DownloadFile downloads the file at the path to the local file
+// with DownloadTo. The download is verified unless the Verify option
+// is VerifyNever. The local file is created if it doesn't exist.
+func (s *ResourcesService) DownloadFile(
+	ctx context.Context,
+	path Path,
+	localPath string,
+	opt *DownloadOptions,
+) (*Resource, error) {
+	if opt == nil {
+		opt = new(DownloadOptions)
+	}
+
+	f, err := os.OpenFile(localPath, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := s.downloadTo(ctx, path, f, opt, true)
+	if resource != nil {
+		// Cut off the data left from a bigger file.
+		err = f.Truncate(int64(resource.Size))
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, ErrChecksumMismatch) && opt.DeleteOnMismatch {
+		os.Remove(localPath)
+	}
+	return resource, err
+}
+
+// downloadTo downloads the file and verifies the download.
+func (s *ResourcesService) downloadTo(
+	ctx context.Context,
+	path Path,
+	dst io.WriterAt,
+	opt *DownloadOptions,
+	fileToFile bool,
+) (*Resource, error) {
+	var verifySrc io.ReaderAt
+	if opt.Verify.enabled(fileToFile) {
+		r, ok := dst.(io.ReaderAt)
+		if !ok {
+			return nil, errors.New("yadisk: verified download destination must implement io.ReaderAt")
+		}
+		verifySrc = r
+	}
+
+	chunkSize := opt.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultDownloadChunkSize
+	}
+	concurrency := opt.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultDownloadConcurrency
+	}
+
+	resource, _, err := s.Get(ctx, path, &ResourcesOptions{
+		Fields: []string{"path", "type", "size", "revision", "md5", "sha256"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resource.Type == "dir" {
+		return nil, fmt.Errorf("yadisk: %s is a folder", path)
+	}
+	size := int64(resource.Size)
+
+	link, _, err := s.GetDownloadLink(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the state of the previous attempt.
+	state := &downloadState{
+		Path:      resource.Path,
+		Revision:  resource.Revision,
+		Size:      size,
+		ChunkSize: chunkSize,
+	}
+	if opt.StatePath != "" {
+		if saved, err := loadDownloadState(opt.StatePath); err == nil &&
+			saved.Path == state.Path &&
+			saved.Revision == state.Revision &&
+			saved.Size == state.Size &&
+			saved.ChunkSize == state.ChunkSize {
+			state = saved
+		}
+	}
+
+	d := &rangeDownload{
+		client:     s.client,
+		resources:  s,
+		ctx:        ctx,
+		path:       path,
+		href:       link.Href,
+		dst:        dst,
+		size:       size,
+		chunkSize:  chunkSize,
+		maxRetries: opt.MaxRetries,
+		bandwidth:  opt.Bandwidth,
+		tracker:    newProgressTracker(opt.Progress, opt.ProgressInterval, size),
+		state:      state,
+		statePath:  opt.StatePath,
+	}
+	if d.maxRetries <= 0 {
+		d.maxRetries = defaultDownloadRetries
+	}
+	err = d.run(concurrency)
+	d.tracker.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.StatePath != "" {
+		os.Remove(opt.StatePath)
+	}
+
+	if verifySrc != nil {
+		sums := newChecksums()
+		if err := sums.readFrom(verifySrc, size); err != nil {
+			return nil, err
+		}
+		if err := sums.verify(path, resource); err != nil {
+			return nil, err
+		}
+	}
+	return resource, nil
+}
+
+// rangeDownload is a single DownloadTo call.
+type rangeDownload struct {
+	client     *Client
+	resources  *ResourcesService
+	ctx        context.Context
+	path       Path
+	dst        io.WriterAt
+	size       int64
+	chunkSize  int64
+	maxRetries int
+	bandwidth  *BandwidthLimiter
+	tracker    *progressTracker
+
+	mu        sync.Mutex
+	state     *downloadState
+	statePath string
+
+	// The download link, renewed when it expires.
+	hrefMu sync.Mutex
+	href   string
+}
+
+// run downloads the chunks which are not done yet.
+func (d *rangeDownload) run(concurrency int) error {
+	done := make(map[int]bool, len(d.state.Done))
+	for _, i := range d.state.Done {
+		done[i] = true
+	}
+	var (
+		pending    []int
+		downloaded int64
+	)
+	chunks := int((d.size + d.chunkSize - 1) / d.chunkSize)
+	for i := 0; i < chunks; i++ {
+		if done[i] {
+			start, end := d.chunkRange(i)
+			downloaded += end - start
+		} else {
+			pending = append(pending, i)
+		}
+	}
+	d.tracker.set(downloaded)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// The first chunk checks whether the server supports ranges.
+	err := d.withRetries(func() error { return d.chunk(pending[0]) })
+	if err == errRangesNotSupported {
+		d.tracker.set(0)
+		return d.withRetries(d.single)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+	d.ctx = ctx
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		queue    = make(chan int)
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if err := d.withRetries(func() error { return d.chunk(i) }); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+	for _, i := range pending[1:] {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return d.ctx.Err()
+}
+
+// chunkRange returns the byte range of the chunk with the index i.
+func (d *rangeDownload) chunkRange(i int) (start, end int64) {
+	start = int64(i) * d.chunkSize
+	end = start + d.chunkSize
+	if end > d.size {
+		end = d.size
+	}
+	return start, end
+}
+
+// stream requests the data in the range, or the whole file if it's
+// empty. An expired link is requested again once, like File does,
+// and the other chunks continue with the new one.
+func (d *rangeDownload) stream(rng string) (*Response, error) {
+	for renewed := false; ; renewed = true {
+		d.hrefMu.Lock()
+		href := d.href
+		d.hrefMu.Unlock()
+
+		req, err := d.client.NewRequestWithContext(
+			WithOperation(d.ctx, "resources.download"),
+			"GET",
+			href,
+			nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+
+		resp, err := d.client.stream(req)
+		if err == nil || renewed || !d.client.isLinkExpired(resp) {
+			return resp, err
+		}
+		if err := d.renewLink(href); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// renewLink requests a new download link
+// unless the expired one has been renewed already.
+func (d *rangeDownload) renewLink(expired string) error {
+	d.hrefMu.Lock()
+	defer d.hrefMu.Unlock()
+	if d.href != expired {
+		return nil
+	}
+	link, _, err := d.resources.GetDownloadLink(d.ctx, d.path)
+	if err != nil {
+		return err
+	}
+	d.href = link.Href
+	return nil
+}
+
+// chunk downloads the chunk with the index i.
+func (d *rangeDownload) chunk(i int) error {
+	start, end := d.chunkRange(i)
+
+	resp, err := d.stream(fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return errRangesNotSupported
+	}
+
+	if err := d.copy(io.NewOffsetWriter(d.dst, start), resp.Body, end-start); err != nil {
+		return err
+	}
+	return d.markDone(i)
+}
+
+// single downloads the whole file in a single stream.
+func (d *rangeDownload) single() error {
+	resp, err := d.stream("")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return d.copy(io.NewOffsetWriter(d.dst, 0), resp.Body, d.size)
+}
+
+// copy copies exactly n bytes from r to w. The progress of
+// a failed copy is discarded, since the data will be requested again.
+func (d *rangeDownload) copy(w io.Writer, r io.Reader, n int64) (err error) {
+	pw := &progressWriter{w: destWriter{w}, tracker: d.tracker}
+	defer func() {
+		if err != nil {
+			d.tracker.add(-pw.written)
+		}
+	}()
+
+	_, err = io.Copy(pw, d.client.throttle(d.ctx, io.LimitReader(r, n), d.bandwidth))
+	d.client.observe(Event{
+		Kind:      EventBytesDownloaded,
+		Operation: operationFromContext(d.ctx),
+		Bytes:     pw.written,
+	})
+	if err != nil {
+		return err
+	}
+	if pw.written != n {
+		return fmt.Errorf("%w: got %d bytes, want %d", ErrSizeMismatch, pw.written, n)
+	}
+
+	// More data than the resource size means the file has changed.
+	if extra, _ := io.CopyN(ioutil.Discard, r, 1); extra > 0 {
+		return fmt.Errorf("%w: got more than %d bytes", ErrSizeMismatch, n)
+	}
+	return nil
+}
+
+// destWriteError is the error of writing to the download destination.
+type destWriteError struct {
+	err error
+}
+
+func (e *destWriteError) Error() string {
+	return e.err.Error()
+}
+
+func (e *destWriteError) Unwrap() error {
+	return e.err
+}
+
+// destWriter wraps the write errors into *destWriteError to tell
+// them from the errors of reading the response.
+type destWriter struct {
+	w io.Writer
+}
+
+func (w destWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		err = &destWriteError{err: err}
+	}
+	return n, err
+}
+
+// markDone records the chunk as downloaded in the state file.
+func (d *rangeDownload) markDone(i int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.state.Done = append(d.state.Done, i)
+	if d.statePath == "" {
+		return nil
+	}
+	return saveDownloadState(d.statePath, d.state)
+}
+
+// withRetries calls f until it succeeds, the retries are exhausted,
+// or an error which can't be fixed by retrying occurs.
+func (d *rangeDownload) withRetries(f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || err == errRangesNotSupported || attempt >= d.maxRetries ||
+			d.ctx.Err() != nil || !isRetryableDownloadError(err) {
+			return err
+		}
+		select {
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		case <-time.After(retryDelay(attempt, nil)):
+		}
+	}
+}
+
+// isRetryableDownloadError reports whether the failed chunk
+// should be requested again. The errors of the destination,
+// like a full disk, aren't fixed by requesting the data again.
+func isRetryableDownloadError(err error) bool {
+	var writeErr *destWriteError
+	if errors.As(err, &writeErr) {
+		return false
+	}
+	if errors.Is(err, ErrSizeMismatch) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// stream sends the request and returns the response with the body
+// left open for reading. The caller must close the body.
+// Unlike Do, the request isn't retried.
+func (c *Client) stream(req *http.Request) (response *Response, err error) {
+	op := operationFromContext(req.Context())
+	done := c.observeRequest(op)
+	defer func() { done(response, err) }()
+
+	start := time.Now()
+	resp, err := c.send(op, req)
+	if err != nil {
+		return nil, err
+	}
+	response = newResponse(resp)
+	response.Latency = time.Since(start)
+
+	if err = checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return response, err
+	}
+
+	return response, nil
+}
+
+// loadDownloadState reads the download state from the file.
+func loadDownloadState(name string) (*downloadState, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	state := new(downloadState)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// saveDownloadState atomically writes the download state to the file.
+func saveDownloadState(name string, state *downloadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,257 @@
+package yadisk
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DownloadDirOptions specifies the optional parameters to the
+// ResourcesService.DownloadDir method.
+type DownloadDirOptions struct {
+	// The number of files downloaded in parallel. Defaults to 4.
+	Concurrency int
+
+	// Bandwidth, if set, limits the total download rate in addition
+	// to the Client Bandwidth.
+	Bandwidth *BandwidthLimiter
+
+	// Verify controls whether the downloaded files are verified,
+	// see DownloadOptions.
+	Verify VerifyMode
+}
+
+// FileDownloadResult is the result of downloading a single file
+// by DownloadDir.
+type FileDownloadResult struct {
+	// Path to the file on Disk and to the local file.
+	Path      Path
+	LocalPath string
+
+	// The size of the file.
+	Size int64
+
+	// Skipped is true if the local file already has the same
+	// size and MD5 hash.
+	Skipped bool
+
+	// The error which occurred while downloading the file.
+	Err error
+}
+
+// DownloadDirReport lists the results of DownloadDir in the walk order.
+type DownloadDirReport struct {
+	Files []FileDownloadResult
+}
+
+// Failed returns the results of the files which failed to download.
+func (r *DownloadDirReport) Failed() []FileDownloadResult {
+	var failed []FileDownloadResult
+	for _, f := range r.Files {
+		if f.Err != nil {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// DownloadDir downloads the contents of the folder at remoteDir into
+// the local folder, mirroring its structure. The folder is listed page
+// by page, the local folders are created, and then the files are
+// downloaded by a pool of workers.
+//
+// Every file is written to a temporary file in the same local folder
+// and renamed when complete, so an existing file is never left partly
+// written. The files whose size and MD5 hash match the remote ones are
+// skipped. The modification times of the files and folders are set
+// to the ones on Disk.
+//
+// The failures of single files don't stop the download, they are
+// listed in the report. The returned error is set only if a folder
+// can't be listed or created, or the context is done.
+func (s *ResourcesService) DownloadDir(
+	ctx context.Context,
+	remoteDir Path,
+	localDir string,
+	opt *DownloadDirOptions,
+) (*DownloadDirReport, error) {
+	if opt == nil {
+		opt = new(DownloadDirOptions)
+	}
+
+	root, _, err := s.Get(ctx, remoteDir, &ResourcesOptions{
+		Fields: []string{"path", "type", "modified"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if root.Type != "dir" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFolder, remoteDir)
+	}
+
+	// List the folders breadth first, creating the local ones.
+	// The resources of the files are kept in the report order.
+	report := &DownloadDirReport{}
+	var resources []Resource
+	dirs := []Resource{*root}
+	localDirs := []string{localDir}
+	for i := 0; i < len(dirs); i++ {
+		if err := os.MkdirAll(localDirs[i], 0755); err != nil {
+			return nil, err
+		}
+		items, err := s.List(ctx, dirs[i].Path, &ListOptions{
+			Fields: []string{"name", "path", "type", "size", "md5", "modified"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if !isValidName(item.Name) {
+				resources = append(resources, item)
+				report.Files = append(report.Files, FileDownloadResult{
+					Path: item.Path,
+					Err:  fmt.Errorf("yadisk: invalid file name %q", item.Name),
+				})
+				continue
+			}
+			localPath := filepath.Join(localDirs[i], item.Name)
+			if item.Type == "dir" {
+				dirs = append(dirs, item)
+				localDirs = append(localDirs, localPath)
+				continue
+			}
+			resources = append(resources, item)
+			report.Files = append(report.Files, FileDownloadResult{
+				Path:      item.Path,
+				LocalPath: localPath,
+				Size:      int64(item.Size),
+			})
+		}
+	}
+
+	concurrency := opt.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultDownloadConcurrency
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f := &report.Files[i]
+				f.Skipped, f.Err = s.downloadDirFile(ctx, &resources[i], f.LocalPath, opt)
+			}
+		}()
+	}
+
+	var files []int
+	for i := range report.Files {
+		if report.Files[i].Err == nil {
+			files = append(files, i)
+		}
+	}
+	for n := range files {
+		select {
+		case jobs <- files[n]:
+			continue
+		case <-ctx.Done():
+		}
+		for _, i := range files[n:] {
+			report.Files[i].Err = ctx.Err()
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Set the folder times after their contents are written.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chtimes(localDirs[i], dirs[i].Modified, dirs[i].Modified)
+	}
+
+	return report, ctx.Err()
+}
+
+// downloadDirFile downloads a single file unless the local one
+// is the same. It reports whether the file is skipped.
+func (s *ResourcesService) downloadDirFile(
+	ctx context.Context,
+	resource *Resource,
+	localPath string,
+	opt *DownloadDirOptions,
+) (bool, error) {
+	if same, err := isSameFile(localPath, resource); err != nil || same {
+		return same, err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(localPath), ".yadisk-*.part")
+	if err != nil {
+		return false, err
+	}
+	_, err = s.downloadTo(ctx, resource.Path, tmp, &DownloadOptions{
+		Concurrency: 1,
+		Bandwidth:   opt.Bandwidth,
+		Verify:      opt.Verify,
+	}, true)
+	if err == nil {
+		// The temporary file is private, the downloaded one isn't.
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(tmp.Name(), resource.Modified, resource.Modified)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), localPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return false, err
+}
+
+// isSameFile reports whether the local file has the size
+// and the MD5 hash of the resource.
+func isSameFile(localPath string, resource *Resource) (bool, error) {
+	info, err := os.Stat(localPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() != int64(resource.Size) || resource.MD5 == "" {
+		return false, nil
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return strings.EqualFold(hex.EncodeToString(h.Sum(nil)), resource.MD5), nil
+}
+
+// isValidName reports whether the resource name
+// can be used as a local file name.
+func isValidName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, `/\`) && path.Base(name) == name
+}
@@ -1,6 +1,59 @@
 package yadisk
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+)
+
+// ErrSizeMismatch is returned when the size of the transferred data
+// differs from the size of the resource.
+var ErrSizeMismatch = errors.New("yadisk: size mismatch")
+
+// ErrNotFolder is returned by ResourcesService.List for a file.
+var ErrNotFolder = errors.New("yadisk: not a folder")
+
+// ErrChecksumMismatch is returned when the checksum of the transferred
+// data differs from the checksum of the resource. The returned error is
+// a *ChecksumError which matches ErrChecksumMismatch with errors.Is.
+var ErrChecksumMismatch = errors.New("yadisk: checksum mismatch")
+
+// ChecksumError describes the checksum mismatch of a transfer.
+type ChecksumError struct {
+	// Path to the resource on Disk.
+	Path Path
+
+	// The hash algorithm, "md5" or "sha256".
+	Algorithm string
+
+	// Hex encoded hashes of the resource and of the local data.
+	Remote string
+	Local  string
+
+	// DeleteErr is the error of deleting the uploaded
+	// resource with the DeleteOnMismatch option, if any.
+	DeleteErr error
+}
+
+func (e *ChecksumError) Error() string {
+	msg := fmt.Sprintf(
+		"yadisk: %s checksum mismatch for %s: remote %s, local %s",
+		e.Algorithm,
+		e.Path,
+		e.Remote,
+		e.Local,
+	)
+	if e.DeleteErr != nil {
+		msg += fmt.Sprintf(" (deleting the resource: %v)", e.DeleteErr)
+	}
+	return msg
+}
+
+// Is reports whether the target is ErrChecksumMismatch.
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
 
 // APIError error can occur if the request was formed incorrectly,
 // the specified resource does not exist on the server,
@@ -13,6 +66,9 @@ import "fmt"
 type APIError struct {
 	Description string `json:"description"`
 	Code        string `json:"error"`
+
+	// HTTP status code of the response.
+	StatusCode int `json:"-"`
 }
 
 func (e *APIError) Error() string {
@@ -25,3 +81,26 @@ func (e *APIError) Error() string {
 		e.Description,
 	)
 }
+
+// hasErrorCode reports whether err is an API error with the code.
+func hasErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// Is reports whether the target is fs.ErrNotExist for the 404 Not Found
+// error and the missing parent folder error, fs.ErrPermission for the
+// 401 Unauthorized and 403 Forbidden errors, or fs.ErrExist for the
+// errors about an existing file or folder, so the API errors can be
+// checked like the file system ones.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e.StatusCode == http.StatusNotFound || e.Code == errCodeParentMissing
+	case fs.ErrPermission:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case fs.ErrExist:
+		return e.Code == errCodeResourceExists || e.Code == errCodeDirExists
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package yadisk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// defaultReadAhead is the minimal number of bytes requested
+// by a single File.Read call.
+const defaultReadAhead = 256 << 10
+
+// Create returns a writer uploading the written data to the file
+// at the path. The upload link is requested before Create returns,
+// so errors like an existing file without the Overwrite option are
+// reported right away. The data is streamed to the uploader while
+// being written, and the upload is finished by Close, which returns
+// the upload error if any. A failed upload also fails the following
+// writes.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/upload-docpage/
+func (s *ResourcesService) Create(
+	ctx context.Context,
+	path Path,
+	opt *UploadOptions,
+) (io.WriteCloser, error) {
+	link, _, err := s.GetUploadLink(ctx, path, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt == nil {
+		opt = new(UploadOptions)
+	}
+
+	pr, pw := io.Pipe()
+	w := &fileWriter{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		_, w.err = s.uploadTo(ctx, path, link, pr, opt)
+		if w.err != nil {
+			pr.CloseWithError(w.err)
+		} else {
+			pr.Close()
+		}
+	}()
+	return w, nil
+}
+
+// fileWriter is the writer returned by ResourcesService.Create.
+type fileWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the upload and waits for the uploader to respond.
+func (w *fileWriter) Close() error {
+	w.pw.Close()
+	<-w.done
+	return w.err
+}
+
+// File is a read-only remote file opened by ResourcesService.Open.
+// The data is fetched with range requests: ReadAt makes a request
+// per call, while Read fetches at least 256 KiB at once and serves
+// the following reads from the buffer. File implements io.ReadSeekCloser
+// and io.ReaderAt, so it can be used with archive/zip.NewReader
+// and the like.
+//
+// ReadAt is safe for concurrent use, Read and Seek are not.
+type File struct {
+	s        *ResourcesService
+	ctx      context.Context
+	path     Path
+	resource *Resource
+
+	mu     sync.Mutex
+	href   string
+	closed bool
+
+	offset int64
+	buf    []byte
+	bufOff int64
+}
+
+// Open opens the file at the path for reading. The context
+// is used for all the requests made by the file.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/content-docpage/
+func (s *ResourcesService) Open(ctx context.Context, path Path) (*File, error) {
+	resource, _, err := s.Get(ctx, path, &ResourcesOptions{
+		Fields: []string{"path", "name", "type", "size", "md5", "sha256", "modified", "revision"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resource.Type == "dir" {
+		return nil, fmt.Errorf("yadisk: %s is a directory", path)
+	}
+
+	link, _, err := s.GetDownloadLink(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{s: s, ctx: ctx, path: path, resource: resource, href: link.Href}, nil
+}
+
+// Stat returns the metainformation of the file taken when it was opened.
+func (f *File) Stat() *Resource {
+	return f.resource
+}
+
+// Size returns the size of the file.
+func (f *File) Size() int64 {
+	return int64(f.resource.Size)
+}
+
+// Read reads up to len(p) bytes from the current offset.
+func (f *File) Read(p []byte) (int, error) {
+	if f.isClosed() {
+		return 0, os.ErrClosed
+	}
+	if f.offset >= f.Size() {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Refill the read-ahead buffer if the offset is outside of it.
+	if f.offset < f.bufOff || f.offset >= f.bufOff+int64(len(f.buf)) {
+		n := int64(len(p))
+		if n < defaultReadAhead {
+			n = defaultReadAhead
+		}
+		if left := f.Size() - f.offset; n > left {
+			n = left
+		}
+		buf := make([]byte, n)
+		read, err := f.ReadAt(buf, f.offset)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		f.buf = buf[:read]
+		f.bufOff = f.offset
+	}
+
+	n := copy(p, f.buf[f.offset-f.bufOff:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+// Seek sets the offset for the next Read.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.isClosed() {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.Size()
+	default:
+		return 0, errors.New("yadisk: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("yadisk: negative offset")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// ReadAt reads len(p) bytes starting at the offset off
+// with a single range request.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.isClosed() {
+		return 0, os.ErrClosed
+	}
+	if off < 0 {
+		return 0, errors.New("yadisk: negative offset")
+	}
+	if off >= f.Size() {
+		return 0, io.EOF
+	}
+
+	want := p
+	if left := f.Size() - off; int64(len(want)) > left {
+		want = want[:left]
+	}
+	if len(want) == 0 {
+		return 0, nil
+	}
+
+	n, err := f.readRange(want, off)
+	if err == nil && len(want) < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close closes the file. The following calls return os.ErrClosed.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	f.buf = nil
+	return nil
+}
+
+func (f *File) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// readRange fills p with the data starting at the offset off.
+// An expired download link is requested again once.
+func (f *File) readRange(p []byte, off int64) (int, error) {
+	ctx := WithOperation(f.ctx, "resources.read")
+
+	for renewed := false; ; renewed = true {
+		f.mu.Lock()
+		href := f.href
+		f.mu.Unlock()
+
+		req, err := f.s.client.NewRequestWithContext(ctx, "GET", href, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+		resp, err := f.s.client.stream(req)
+		if err != nil {
+			if renewed || !f.s.client.isLinkExpired(resp) {
+				return 0, err
+			}
+			link, _, err := f.s.GetDownloadLink(f.ctx, f.path)
+			if err != nil {
+				return 0, err
+			}
+			f.mu.Lock()
+			f.href = link.Href
+			f.mu.Unlock()
+			continue
+		}
+		defer resp.Body.Close()
+
+		// Skip the data before the offset if the whole file is sent.
+		body := io.Reader(resp.Body)
+		if resp.StatusCode != http.StatusPartialContent {
+			if _, err := io.CopyN(ioutil.Discard, body, off); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := io.ReadFull(f.s.client.throttle(ctx, body, nil), p)
+		f.s.client.observe(Event{
+			Kind:       EventBytesDownloaded,
+			Operation:  "resources.read",
+			StatusCode: resp.StatusCode,
+			Bytes:      int64(n),
+		})
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("%w: got %d bytes, want %d", ErrSizeMismatch, n, len(p))
+		}
+		return n, err
+	}
+}
@@ -0,0 +1,167 @@
+package yadisk
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Glob returns the files and folders matching the pattern, sorted by
+// path. The pattern is a path, like "/photos/20??/*.jpg", which may have
+// a namespace, like "app:/cache/*" or "trash:/*.doc". Every path element
+// is matched with the path.Match syntax: "*", "?", character classes
+// and escapes. The "**" element matches any number of folders, and at
+// the end of the pattern, everything below the folder.
+//
+// Only the folders needed to match the pattern are listed: the literal
+// elements are requested directly, except in the Trash, and every
+// folder is listed once even if several elements match it. A missing
+// path is not an error, the only possible error is path.ErrBadPattern
+// or a request error.
+func (s *ResourcesService) Glob(ctx context.Context, pattern string) ([]Resource, error) {
+	prefix, rest := "", pattern
+	if i := strings.Index(pattern, ":"); i >= 0 && !strings.ContainsAny(pattern[:i], "/*?[\\") {
+		prefix, rest = pattern[:i+1], pattern[i+1:]
+	}
+	var elems []string
+	for _, elem := range strings.Split(rest, "/") {
+		if elem == "" || elem == "." {
+			continue
+		}
+		if _, err := path.Match(elem, ""); err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+
+	// Skip the listing of the folders given literally, except in
+	// the Trash, where the paths of the resources differ from their names.
+	base := Path(prefix + "/")
+	for base.Namespace() != NamespaceTrash && len(elems) > 0 && !hasMeta(elems[0]) {
+		base = base.Join(elems[0])
+		elems = elems[1:]
+	}
+	resource, err := s.getAny(ctx, base, &ResourcesOptions{Limit: 1})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(elems) == 0 {
+		resource.Embedded = nil
+		return []Resource{*resource}, nil
+	}
+
+	g := &globber{s: s, ctx: ctx, listings: make(map[Path][]Resource), found: make(map[Path]Resource)}
+	if err := g.match(resource, elems); err != nil {
+		return nil, err
+	}
+	matches := make([]Resource, 0, len(g.found))
+	for _, r := range g.found {
+		matches = append(matches, r)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Path < matches[j].Path
+	})
+	return matches, nil
+}
+
+// globber keeps the state of a Glob.
+type globber struct {
+	s   *ResourcesService
+	ctx context.Context
+
+	// The contents of the listed folders by path.
+	listings map[Path][]Resource
+
+	// The matching resources by path.
+	found map[Path]Resource
+}
+
+// list returns the contents of the folder, or nil
+// if the folder doesn't exist or is a file.
+func (g *globber) list(dir Path) ([]Resource, error) {
+	if items, ok := g.listings[dir]; ok {
+		return items, nil
+	}
+	items, err := g.s.List(g.ctx, dir, nil)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrNotFolder) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	g.listings[dir] = items
+	return items, nil
+}
+
+// match adds the resources below the folder matching the elements.
+func (g *globber) match(dir *Resource, elems []string) error {
+	if dir.Type != "dir" {
+		return nil
+	}
+	elem, rest := elems[0], elems[1:]
+
+	// Request the literal elements directly. The Trash is listed instead,
+	// since the paths of its resources differ from their names.
+	if !hasMeta(elem) && elem != "**" && dir.Path.Namespace() != NamespaceTrash {
+		child := dir.Path.Join(elem)
+		if len(rest) > 0 {
+			return g.match(&Resource{Path: child, Type: "dir"}, rest)
+		}
+		resource, err := g.s.getAny(g.ctx, child, &ResourcesOptions{Limit: 1})
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		}
+		resource.Embedded = nil
+		g.found[resource.Path] = *resource
+		return nil
+	}
+
+	items, err := g.list(dir.Path)
+	if err != nil {
+		return err
+	}
+
+	if elem == "**" {
+		// Match no folders, then one or more.
+		if len(rest) == 0 {
+			for _, item := range items {
+				g.found[item.Path] = item
+			}
+		} else if err := g.match(dir, rest); err != nil {
+			return err
+		}
+		for i := range items {
+			if err := g.match(&items[i], elems); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := range items {
+		if ok, _ := path.Match(elem, items[i].Name); !ok {
+			continue
+		}
+		if len(rest) == 0 {
+			g.found[items[i].Path] = items[i]
+		} else if err := g.match(&items[i], rest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasMeta reports whether the pattern element has
+// the special characters of path.Match.
+func hasMeta(elem string) bool {
+	return strings.ContainsAny(elem, `*?[\`)
+}
@@ -0,0 +1,201 @@
+package yadisk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Link is an object containing a URL for requesting
+// resource metainformation or for following the asynchronous operation.
+// https://tech.yandex.com/disk/api/reference/response-objects-docpage/#link
+type Link struct {
+	// URL. It may be a URL template; see the Templated key.
+	Href string `json:"href"`
+
+	// The HTTP method for requesting the URL from the Href key.
+	Method string `json:"method"`
+
+	// Indicates a URL template according to RFC 6570.
+	Templated bool `json:"templated"`
+}
+
+// Expand returns the link URL with the template expressions replaced
+// by the values. Variables missing in values are treated as undefined
+// and are omitted according to RFC 6570. The URL of a link which isn't
+// templated is returned without changes.
+func (l *Link) Expand(values map[string]string) (string, error) {
+	if !l.Templated {
+		return l.Href, nil
+	}
+	return expandTemplate(l.Href, values)
+}
+
+// Follow sends the request described by the link and stores the
+// JSON decoded response in the value pointed to by v the same way
+// as Do does. Templated links are expanded without variables,
+// use Expand and NewRequestWithContext to provide them.
+func (c *Client) Follow(ctx context.Context, link *Link, v interface{}) (*Response, error) {
+	href, err := link.Expand(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	method := link.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	req, err := c.NewRequestWithContext(ctx, method, href, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(req, v)
+}
+
+// templateOperator describes the expansion rules for an
+// expression operator, as listed in RFC 6570 appendix A.
+type templateOperator struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool
+}
+
+var templateOperators = map[byte]templateOperator{
+	'+': {first: "", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+	'#': {first: "#", sep: ",", reserved: true},
+}
+
+// expandTemplate expands the RFC 6570 URI template with string values.
+func expandTemplate(template string, values map[string]string) (string, error) {
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			buf.WriteString(template)
+			return buf.String(), nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("yadisk: unclosed expression in URI template %q", template)
+		}
+		end += start
+
+		buf.WriteString(template[:start])
+		expanded, err := expandExpression(template[start+1:end], values)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(expanded)
+		template = template[end+1:]
+	}
+}
+
+// expandExpression expands a single template expression
+// without the enclosing braces.
+func expandExpression(expr string, values map[string]string) (string, error) {
+	if expr == "" {
+		return "", fmt.Errorf("yadisk: empty expression in URI template")
+	}
+
+	op := templateOperator{sep: ","}
+	if o, ok := templateOperators[expr[0]]; ok {
+		op = o
+		expr = expr[1:]
+	}
+
+	var parts []string
+	for _, spec := range strings.Split(expr, ",") {
+		name, maxLen, err := parseVarSpec(spec)
+		if err != nil {
+			return "", err
+		}
+
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		if maxLen > 0 && maxLen < len([]rune(value)) {
+			value = string([]rune(value)[:maxLen])
+		}
+
+		var part strings.Builder
+		if op.named {
+			part.WriteString(name)
+			if value == "" {
+				part.WriteString(op.ifEmpty)
+				parts = append(parts, part.String())
+				continue
+			}
+			part.WriteByte('=')
+		}
+		part.WriteString(encodeTemplateValue(value, op.reserved))
+		parts = append(parts, part.String())
+	}
+
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return op.first + strings.Join(parts, op.sep), nil
+}
+
+// parseVarSpec parses the variable name and the prefix modifier length.
+// The explode modifier doesn't change the expansion of string values
+// and is ignored.
+func parseVarSpec(spec string) (name string, maxLen int, err error) {
+	name = strings.TrimSuffix(spec, "*")
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		maxLen, err = strconv.Atoi(name[i+1:])
+		if err != nil || maxLen <= 0 || maxLen >= 10000 {
+			return "", 0, fmt.Errorf("yadisk: invalid prefix modifier in URI template variable %q", spec)
+		}
+		name = name[:i]
+	}
+	if name == "" {
+		return "", 0, fmt.Errorf("yadisk: empty variable name in URI template")
+	}
+	return name, maxLen, nil
+}
+
+// encodeTemplateValue percent-encodes the value. Unreserved characters
+// are always kept, reserved characters and percent-encoded triplets
+// are kept when reserved is true.
+func encodeTemplateValue(value string, reserved bool) string {
+	const hex = "0123456789ABCDEF"
+
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case isUnreserved(c):
+			buf.WriteByte(c)
+		case reserved && strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0:
+			buf.WriteByte(c)
+		case reserved && c == '%' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]):
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte('%')
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&15])
+		}
+	}
+	return buf.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,135 @@
+// Package metrics provides a Prometheus collector for the Yandex.Disk client.
+//
+// It lives in its own package so the yadisk package doesn't depend
+// on the Prometheus client library. Usage:
+//
+//	collector := metrics.NewCollector("myapp")
+//	prometheus.MustRegister(collector)
+//
+//	client := yadisk.NewClient("ACCESS_TOKEN")
+//	client.Observer = collector
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector exposing the client usage metrics.
+// It implements yadisk.Observer and should be set as the Observer
+// of one or more clients.
+type Collector struct {
+	requests   *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	inFlight   prometheus.Gauge
+	retries    *prometheus.CounterVec
+	throttled  *prometheus.CounterVec
+	uploaded   prometheus.Counter
+	downloaded prometheus.Counter
+}
+
+// NewCollector returns a new Collector. The metric names are prefixed
+// with the namespace, if it's not empty, and "yadisk".
+func NewCollector(namespace string) *Collector {
+	const subsystem = "yadisk"
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of finished Yandex.Disk API requests by operation and status.",
+		}, []string{"operation", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of Yandex.Disk API requests by operation and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_in_flight",
+			Help:      "Number of Yandex.Disk API requests being currently sent.",
+		}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "retries_total",
+			Help:      "Number of retried Yandex.Disk API requests by operation.",
+		}, []string{"operation"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "throttled_total",
+			Help:      "Number of Yandex.Disk API responses with 429 Too Many Requests by operation.",
+		}, []string{"operation"}),
+		uploaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "uploaded_bytes_total",
+			Help:      "Number of bytes sent to Yandex.Disk.",
+		}),
+		downloaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "downloaded_bytes_total",
+			Help:      "Number of bytes received from Yandex.Disk.",
+		}),
+	}
+}
+
+// Observe implements yadisk.Observer.
+func (c *Collector) Observe(e yadisk.Event) {
+	switch e.Kind {
+	case yadisk.EventRequestStarted:
+		c.inFlight.Inc()
+	case yadisk.EventRequestDone:
+		c.inFlight.Dec()
+		status := statusLabel(e.StatusCode)
+		c.requests.WithLabelValues(e.Operation, status).Inc()
+		c.duration.WithLabelValues(e.Operation, status).Observe(e.Duration.Seconds())
+	case yadisk.EventRetry:
+		c.retries.WithLabelValues(e.Operation).Inc()
+	case yadisk.EventThrottled:
+		c.throttled.WithLabelValues(e.Operation).Inc()
+	case yadisk.EventBytesUploaded:
+		c.uploaded.Add(float64(e.Bytes))
+	case yadisk.EventBytesDownloaded:
+		c.downloaded.Add(float64(e.Bytes))
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+	c.inFlight.Describe(ch)
+	c.retries.Describe(ch)
+	c.throttled.Describe(ch)
+	c.uploaded.Describe(ch)
+	c.downloaded.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+	c.inFlight.Collect(ch)
+	c.retries.Collect(ch)
+	c.throttled.Collect(ch)
+	c.uploaded.Collect(ch)
+	c.downloaded.Collect(ch)
+}
+
+// statusLabel returns the status label value for the HTTP status code.
+// Requests which failed without a response are labeled "error".
+func statusLabel(code int) string {
+	if code == 0 {
+		return "error"
+	}
+	return strconv.Itoa(code)
+}
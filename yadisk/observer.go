@@ -0,0 +1,128 @@
+package yadisk
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind identifies what happened in the client when an Event is reported.
+type EventKind int
+
+const (
+	// EventRequestStarted is reported right before an API request is sent.
+	EventRequestStarted EventKind = iota
+
+	// EventRequestDone is reported when an API request has finished,
+	// successfully or not. Every EventRequestStarted is followed
+	// by exactly one EventRequestDone.
+	EventRequestDone
+
+	// EventRetry is reported before a failed request is sent again.
+	EventRetry
+
+	// EventThrottled is reported when the API responds
+	// with 429 Too Many Requests.
+	EventThrottled
+
+	// EventBytesUploaded is reported when a request body has been sent.
+	EventBytesUploaded
+
+	// EventBytesDownloaded is reported when a raw response body
+	// has been received.
+	EventBytesDownloaded
+)
+
+// Event describes something that happened while talking to the API.
+type Event struct {
+	Kind EventKind
+
+	// Operation is the name of the API operation, for example "disk.get".
+	// Requests made without an operation name are reported as "other".
+	Operation string
+
+	// StatusCode is the HTTP status code of the response.
+	// It is zero if no response has been received.
+	StatusCode int
+
+	// Duration is the time spent on the request.
+	// It is set only for EventRequestDone.
+	Duration time.Duration
+
+	// Bytes is the number of transferred bytes.
+	// It is set only for EventBytesUploaded and EventBytesDownloaded.
+	Bytes int64
+
+	// Err is the error the request finished with, if any.
+	Err error
+}
+
+// Observer receives events about the client activity.
+// It can be used to collect metrics or to log requests.
+// Observe is called synchronously and may be called
+// from several goroutines at once.
+type Observer interface {
+	Observe(e Event)
+}
+
+type operationKey struct{}
+
+// WithOperation returns a copy of ctx carrying the operation name which
+// is reported to the Observer for the requests made with this context.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// operationFromContext returns the operation name stored in ctx.
+func operationFromContext(ctx context.Context) string {
+	if op, ok := ctx.Value(operationKey{}).(string); ok && op != "" {
+		return op
+	}
+	return "other"
+}
+
+// observe reports e to the client Observer if it's set.
+func (c *Client) observe(e Event) {
+	if c.Observer != nil {
+		c.Observer.Observe(e)
+	}
+}
+
+// observeRequest reports the start of a request attempt and returns
+// the function reporting its end with the response and the error.
+func (c *Client) observeRequest(op string) func(response *Response, err error) {
+	c.observe(Event{Kind: EventRequestStarted, Operation: op})
+	start := time.Now()
+	return func(response *Response, err error) {
+		done := Event{
+			Kind:      EventRequestDone,
+			Operation: op,
+			Duration:  time.Since(start),
+			Err:       err,
+		}
+		if response != nil {
+			done.StatusCode = response.StatusCode
+		}
+		c.observe(done)
+	}
+}
+
+// countingBody counts the bytes read from the request body.
+// The Transport may read it after the response is received,
+// so the count is atomic.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	return n, err
+}
+
+// count returns the number of the bytes read so far.
+func (b *countingBody) count() int64 {
+	return atomic.LoadInt64(&b.n)
+}
@@ -0,0 +1,182 @@
+package yadisk
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Namespace is the part of Disk a Path belongs to.
+type Namespace string
+
+// The namespaces of the paths.
+const (
+	NamespaceDisk  Namespace = "disk"
+	NamespaceApp   Namespace = "app"
+	NamespaceTrash Namespace = "trash"
+)
+
+// Limits on the length of the paths and names, in characters.
+const (
+	maxPathLength = 32760
+	maxNameLength = 255
+)
+
+// ErrInvalidPath is returned for the paths which can't exist on Disk.
+var ErrInvalidPath = errors.New("yadisk: invalid path")
+
+// Path is a path on Disk, like "/photos/2020", "disk:/photos/2020",
+// "app:/cache" or "trash:/foo_1408546879". The paths without
+// a namespace are on Disk. The service methods accept any Path as is,
+// and the paths of the resources are returned with the namespace,
+// so compare them with Equal rather than ==.
+type Path string
+
+// ParsePath returns the clean path of s, or an error
+// if it can't be a path on Disk, see Path.Validate.
+func ParsePath(s string) (Path, error) {
+	p := Path(s)
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	return p.Clean(), nil
+}
+
+// String returns the path as is.
+func (p Path) String() string {
+	return string(p)
+}
+
+// split returns the namespace and the path within it. The namespace
+// is empty if the path has none.
+func (p Path) split() (Namespace, string) {
+	s := string(p)
+	if i := strings.Index(s, ":"); i >= 0 {
+		switch ns := Namespace(s[:i]); ns {
+		case NamespaceDisk, NamespaceApp, NamespaceTrash:
+			return ns, s[i+1:]
+		}
+	}
+	return "", s
+}
+
+// Namespace returns the namespace of the path, NamespaceDisk
+// if it has none.
+func (p Path) Namespace() Namespace {
+	if ns, _ := p.split(); ns != "" {
+		return ns
+	}
+	return NamespaceDisk
+}
+
+// Rel returns the clean path within the namespace, like "/photos/2020".
+func (p Path) Rel() string {
+	_, rel := p.split()
+	return path.Clean("/" + rel)
+}
+
+// Clean returns the path with the duplicate slashes, "." and ".."
+// elements removed, like path.Clean. The namespace is kept if the
+// path has one, ".." can't leave the root.
+func (p Path) Clean() Path {
+	ns, _ := p.split()
+	if ns == "" {
+		return Path(p.Rel())
+	}
+	return Path(string(ns) + ":" + p.Rel())
+}
+
+// Canonical returns the clean path with the namespace,
+// like "disk:/photos" for "/photos/".
+func (p Path) Canonical() Path {
+	return Path(string(p.Namespace()) + ":" + p.Rel())
+}
+
+// Equal reports whether the paths point to the same resource,
+// like "/photos" and "disk:/photos/".
+func (p Path) Equal(q Path) bool {
+	return p.Canonical() == q.Canonical()
+}
+
+// Join returns the path with the elements appended, cleaned.
+func (p Path) Join(elem ...string) Path {
+	ns, _ := p.split()
+	rel := path.Join(append([]string{p.Rel()}, elem...)...)
+	if ns == "" {
+		return Path(rel)
+	}
+	return Path(string(ns) + ":" + rel)
+}
+
+// Base returns the last element of the path, or "/" for the root.
+func (p Path) Base() string {
+	return path.Base(p.Rel())
+}
+
+// Dir returns the parent folder of the path, cleaned.
+// The parent of the root is the root.
+func (p Path) Dir() Path {
+	return p.Join("..")
+}
+
+// IsRoot reports whether the path is the root of its namespace.
+func (p Path) IsRoot() bool {
+	return p.Rel() == "/"
+}
+
+// HasPrefix reports whether the path is the folder dir
+// or is inside it, in the same namespace.
+func (p Path) HasPrefix(dir Path) bool {
+	if p.Namespace() != dir.Namespace() {
+		return false
+	}
+	rel, dirRel := p.Rel(), dir.Rel()
+	return dirRel == "/" || rel == dirRel || strings.HasPrefix(rel, dirRel+"/")
+}
+
+// TrashID returns the unique ID Disk appends to the names of the
+// resources in the Trash, like "1408546879" for "trash:/foo_1408546879"
+// or "trash:/foo_1408546879/bar". It reports false for the other paths.
+func (p Path) TrashID() (string, bool) {
+	if p.Namespace() != NamespaceTrash || p.IsRoot() {
+		return "", false
+	}
+	top := strings.SplitN(strings.TrimPrefix(p.Rel(), "/"), "/", 2)[0]
+	i := strings.LastIndex(top, "_")
+	if i < 0 || i == len(top)-1 {
+		return "", false
+	}
+	for _, r := range top[i+1:] {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return "", false
+		}
+	}
+	return top[i+1:], true
+}
+
+// Validate checks that the path is valid UTF-8 without control
+// characters, and that the path and its names aren't longer than
+// Disk allows. The errors match ErrInvalidPath with errors.Is.
+func (p Path) Validate() error {
+	s := string(p)
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("%w %q: not valid UTF-8", ErrInvalidPath, s)
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("%w %q: control character %U", ErrInvalidPath, s, r)
+		}
+	}
+	rel := p.Rel()
+	if utf8.RuneCountInString(rel) > maxPathLength {
+		return fmt.Errorf("%w %q: longer than %d characters", ErrInvalidPath, s, maxPathLength)
+	}
+	for _, name := range strings.Split(rel, "/") {
+		if utf8.RuneCountInString(name) > maxNameLength {
+			return fmt.Errorf("%w %q: name longer than %d characters", ErrInvalidPath, s, maxNameLength)
+		}
+	}
+	return nil
+}
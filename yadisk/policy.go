@@ -0,0 +1,142 @@
+package yadisk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrPolicyViolation is returned by the clients with a Policy for the
+// requests the policy forbids. The returned error is a *PolicyError
+// which matches ErrPolicyViolation with errors.Is.
+var ErrPolicyViolation = errors.New("yadisk: policy violation")
+
+// Policy restricts the requests made by a client, see Client.WithPolicy.
+type Policy struct {
+	// ReadOnly rejects all the requests except GET and HEAD,
+	// including the uploads to the links returned by Disk.
+	// The upload link requests are rejected too.
+	ReadOnly bool
+
+	// Allow is the list of the folders the requests are restricted to,
+	// like "/photos" or "app:/". The paths in the folders are allowed,
+	// see Path.HasPrefix. Empty Allow means any path.
+	Allow []Path
+
+	// parent is the policy of the client the policy is added to.
+	parent *Policy
+}
+
+// PolicyError describes the request rejected by a Policy.
+type PolicyError struct {
+	// The method and the API endpoint of the request, like "disk/resources".
+	Method   string
+	Endpoint string
+
+	// The rejected path, empty if the request is rejected by its method
+	// or because it has no path.
+	Path Path
+
+	// Why the request is rejected, like "read-only mode".
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("yadisk: policy violation: %s %s: %s", e.Method, e.Endpoint, e.Reason)
+}
+
+// Is reports whether the target is ErrPolicyViolation.
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// WithPolicy returns a copy of the client enforcing the policy. The
+// policy is checked when the requests are created, so the forbidden
+// requests fail with ErrPolicyViolation before being sent, and the
+// service methods added later are covered too. The copy shares the
+// HTTP client and the other settings with c.
+//
+// If c has a policy already, both are enforced: the copy is read-only
+// if either policy is, and a path must be allowed by both. So a client
+// with a policy can't be used to make a less restricted one.
+//
+// The "path" and "from" query parameters of the API requests are
+// checked against Allow. The Trash requests without a path refer to
+// the whole Trash, "trash:/", and the other requests for the resources
+// without a path, like the list of all files, are rejected. The paths
+// within the public resources and the requests to the other hosts,
+// like the upload and download links, aren't checked. For a client
+// scoped to the application folder, the paths are checked after they
+// are rewritten, like "app:/cache".
+func (c *Client) WithPolicy(policy Policy) *Client {
+	limited := *c
+	policy.Allow = append([]Path(nil), policy.Allow...)
+	policy.parent = c.policy
+	limited.policy = &policy
+	limited.Disk = &DiskService{client: &limited}
+	limited.Resources = &ResourcesService{client: &limited}
+	return &limited
+}
+
+// uploadEndpoint is the endpoint of the upload links, requested with GET.
+const uploadEndpoint = "disk/resources/upload"
+
+// check returns a *PolicyError if the policy or the policies
+// it's added to forbid the request.
+func (p *Policy) check(c *Client, req *http.Request) error {
+	for ; p != nil; p = p.parent {
+		if err := p.checkOwn(c, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOwn returns a *PolicyError if the policy forbids the request.
+func (p *Policy) checkOwn(c *Client, req *http.Request) error {
+	endpoint, isAPI := c.endpoint(req)
+	if !isAPI {
+		endpoint = req.URL.Host
+	}
+	if p.ReadOnly && (req.Method != "GET" && req.Method != "HEAD" || endpoint == uploadEndpoint) {
+		return &PolicyError{Method: req.Method, Endpoint: endpoint, Reason: "read-only mode"}
+	}
+	if len(p.Allow) == 0 || !isAPI || strings.HasPrefix(endpoint, "disk/public/") {
+		return nil
+	}
+
+	q := req.URL.Query()
+	paths := append(q["path"], q["from"]...)
+	if len(q["path"]) == 0 {
+		switch {
+		case strings.HasPrefix(endpoint, "disk/trash/"):
+			paths = append(paths, "trash:/")
+		case strings.HasPrefix(endpoint, "disk/resources"):
+			// Like the flat list of all files, which
+			// isn't limited to the allowed folders.
+			return &PolicyError{Method: req.Method, Endpoint: endpoint, Reason: "no path to check"}
+		}
+	}
+	for _, s := range paths {
+		if !p.allows(Path(s)) {
+			return &PolicyError{
+				Method:   req.Method,
+				Endpoint: endpoint,
+				Path:     Path(s),
+				Reason:   fmt.Sprintf("path %s is not allowed", s),
+			}
+		}
+	}
+	return nil
+}
+
+// allows reports whether the path is in one of the allowed folders.
+func (p *Policy) allows(path Path) bool {
+	for _, dir := range p.Allow {
+		if path.HasPrefix(dir) {
+			return true
+		}
+	}
+	return false
+}
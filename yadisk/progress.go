@@ -0,0 +1,203 @@
+package yadisk
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultProgressInterval is the minimal time between
+// progress reports if not set in the options.
+const defaultProgressInterval = 500 * time.Millisecond
+
+// Progress describes the state of a file transfer.
+type Progress struct {
+	// The number of bytes transferred so far.
+	Done int64
+
+	// The total number of bytes to transfer. It is taken from
+	// the resource size or the Content-Length header, and is -1
+	// if unknown.
+	Total int64
+
+	// The transfer rate since the previous report, in bytes per second.
+	Rate float64
+
+	// The average transfer rate since the start, in bytes per second.
+	AverageRate float64
+
+	// The time passed since the start of the transfer.
+	Elapsed time.Duration
+
+	// The estimated time left, based on the average rate.
+	// It is -1 if it can't be estimated.
+	ETA time.Duration
+
+	// Final is set in the report made when the transfer
+	// finishes, successfully or not.
+	Final bool
+}
+
+// ProgressFunc is called with the transfer progress. It is called
+// at most once per progress interval, and once more when the transfer
+// finishes. It may be called from several goroutines, but never
+// concurrently for the same transfer.
+type ProgressFunc func(p Progress)
+
+// ProgressChan returns a ProgressFunc sending the progress to ch.
+// Reports are dropped instead of blocking the transfer if ch isn't
+// ready to receive, except for the Final one, so ch must be read
+// until the final report is received.
+func ProgressChan(ch chan<- Progress) ProgressFunc {
+	return func(p Progress) {
+		if p.Final {
+			ch <- p
+			return
+		}
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// progressTracker counts the transferred bytes and reports
+// the progress. A nil tracker does nothing.
+type progressTracker struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	interval time.Duration
+	total    int64
+	done     int64
+	start    time.Time
+	last     time.Time
+	lastDone int64
+	finished bool
+}
+
+// newProgressTracker returns a tracker reporting to fn,
+// or nil if fn is nil.
+func newProgressTracker(fn ProgressFunc, interval time.Duration, total int64) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	now := time.Now()
+	return &progressTracker{
+		fn:       fn,
+		interval: interval,
+		total:    total,
+		start:    now,
+		last:     now,
+	}
+}
+
+// add adds n bytes, which can be negative for discarded data,
+// to the transferred ones.
+func (t *progressTracker) add(n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done += n
+	t.report(false)
+}
+
+// set sets the number of transferred bytes.
+func (t *progressTracker) set(n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = n
+	t.report(false)
+}
+
+// finish reports the final progress once.
+func (t *progressTracker) finish() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return
+	}
+	t.report(true)
+	t.finished = true
+}
+
+// report calls the progress function if the interval has passed
+// since the previous report or force is true. Nothing is reported
+// after the final report.
+func (t *progressTracker) report(force bool) {
+	if t.finished {
+		return
+	}
+	now := time.Now()
+	since := now.Sub(t.last)
+	if !force && since < t.interval {
+		return
+	}
+
+	p := Progress{
+		Done:    t.done,
+		Total:   t.total,
+		Elapsed: now.Sub(t.start),
+		ETA:     -1,
+		Final:   force,
+	}
+	if since > 0 {
+		p.Rate = float64(t.done-t.lastDone) / since.Seconds()
+	}
+	if p.Elapsed > 0 {
+		p.AverageRate = float64(t.done) / p.Elapsed.Seconds()
+	}
+	if p.Total >= 0 && p.AverageRate > 0 {
+		left := float64(p.Total-p.Done) / p.AverageRate
+		p.ETA = time.Duration(left * float64(time.Second))
+	}
+
+	t.last = now
+	t.lastDone = t.done
+	t.fn(p)
+}
+
+// progressReader counts the bytes read from the underlying reader.
+type progressReader struct {
+	r       io.Reader
+	tracker *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.tracker.add(int64(n))
+	return n, err
+}
+
+// progressWriter counts the bytes written to the underlying writer.
+type progressWriter struct {
+	w       io.Writer
+	tracker *progressTracker
+	written int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	w.tracker.add(int64(n))
+	return n, err
+}
+
+// readerSize returns the number of bytes left in r
+// if it can be determined, or -1.
+func readerSize(r io.Reader) int64 {
+	if v, ok := r.(interface{ Len() int }); ok {
+		return int64(v.Len())
+	}
+	return -1
+}
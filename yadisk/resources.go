@@ -2,8 +2,13 @@ package yadisk
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/google/go-querystring/query"
 )
 
 // Resource is a description or metainformation about a file or folder.
@@ -51,7 +56,7 @@ type Resource struct {
 	// (for example, trash:/foo_1408546879).
 	// Using this ID, the resource can be differentiated from other
 	// deleted resources with the same name.
-	Path string `json:"path"`
+	Path Path `json:"path"`
 
 	// MD5 hash of the file.
 	MD5 string `json:"md5"`
@@ -98,7 +103,7 @@ type ResourceList struct {
 	// The path to the folder whose contents are described
 	// in this ResourceList object.
 	// For a public folder, the value of the attribute is always "/".
-	Path string `json:"path"`
+	Path Path `json:"path"`
 
 	// The maximum number of items in the items array; set in the request.
 	Limit uint `json:"limit"`
@@ -131,12 +136,12 @@ type ResourcesOptions struct {
 	//
 	// To sort in reverse order, add a hyphen to the value of the parameter,
 	// for example: sort="-name".
-	Sort string `url:"sort"`
+	Sort string `url:"sort,omitempty"`
 
 	// The number of resources in the folder that should be described
 	// in the response (for example, for paginated output).
 	// The default value is 20.
-	Limit uint `url:"limit"`
+	Limit uint `url:"limit,omitempty"`
 
 	// The number of resources from the top of the list that
 	// should be skipped in the response (for example, for paginated output).
@@ -145,7 +150,7 @@ type ResourcesOptions struct {
 	// If we request metainformation about the folder with the offset=1
 	// parameter and default sorting, the Yandex.Disk API returns
 	// only the descriptions of the second and third files.
-	Offset uint `url:"offset"`
+	Offset uint `url:"offset,omitempty"`
 
 	// List of JSON keys that should be included in the response.
 	// Keys that are not included in this list will be discarded when
@@ -154,7 +159,7 @@ type ResourcesOptions struct {
 	//
 	// Embedded keys should be separated by dots.
 	// For example: ["name", "_embedded.items.path"].
-	Fields []string `url:"fields"`
+	Fields []string `url:"fields,comma,omitempty"`
 
 	// The required size of the reduced image (file preview),
 	// which the API returns a reference to in the preview key.
@@ -193,7 +198,7 @@ type ResourcesOptions struct {
 	//   the maximum size in the set proportions of width
 	//   to height (in the example, this is 1/2).
 	//   Then the cropped section is scaled to the specified dimensions.
-	PreviewSize string `url:"preview_size"`
+	PreviewSize string `url:"preview_size,omitempty"`
 
 	// This parameter cuts the preview to the size specified
 	// in the PreviewSize parameter. When set to false (default setting),
@@ -207,7 +212,7 @@ type ResourcesOptions struct {
 	//   a section is cut from the center of the source image with the
 	//   maximum size in the set proportions of width to height.
 	//   Then the cropped section is scaled to the specified dimensions.
-	PreviewCrop bool `url:"preview_crop"`
+	PreviewCrop bool `url:"preview_crop,omitempty"`
 }
 
 // Get retunes metainformation for the path. The path to the desired resource
@@ -217,20 +222,342 @@ type ResourcesOptions struct {
 // Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/meta-docpage/
 func (s *ResourcesService) Get(
 	ctx context.Context,
-	path string,
+	path Path,
 	opt *ResourcesOptions,
-) (*Resource, *http.Response, error) {
-	url := "resources"
-	req, err := s.client.NewRequest("GET", url, nil)
+) (*Resource, *Response, error) {
+	params, err := query.Values(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.Set("path", string(path))
+
+	url := "disk/resources"
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.get"),
+		"GET",
+		url,
+		nil,
+		WithQuery(params),
+	)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	resource := new(Resource)
-	resp, err := s.client.Do(ctx, req, resource)
+	resp, err := s.client.Do(req, resource)
 	if err != nil {
 		return nil, resp, err
 	}
 
 	return resource, resp, nil
 }
+
+// GetTrash returns metainformation for the path in the Trash,
+// like "trash:/" or "trash:/photos_1e2b4c". It takes the same
+// options as Get.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/trash-delete-docpage/
+func (s *ResourcesService) GetTrash(
+	ctx context.Context,
+	path Path,
+	opt *ResourcesOptions,
+) (*Resource, *Response, error) {
+	params, err := query.Values(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.Set("path", string(path))
+
+	url := "disk/trash/resources"
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.get_trash"),
+		"GET",
+		url,
+		nil,
+		WithQuery(params),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resource := new(Resource)
+	resp, err := s.client.Do(req, resource)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return resource, resp, nil
+}
+
+// getAny returns metainformation for the path on Disk
+// or, for the "trash:" paths, in the Trash.
+func (s *ResourcesService) getAny(ctx context.Context, path Path, opt *ResourcesOptions) (*Resource, error) {
+	get := s.Get
+	if path.Namespace() == NamespaceTrash {
+		get = s.GetTrash
+	}
+	resource, _, err := get(ctx, path, opt)
+	return resource, err
+}
+
+// DeleteOptions specifies the optional parameters to the
+// ResourcesService.Delete method.
+type DeleteOptions struct {
+	// Delete the file or folder permanently, without moving it to the Trash.
+	Permanently bool `url:"permanently,omitempty"`
+}
+
+// Delete deletes the file or folder at the path. Folders are deleted
+// asynchronously, the link to the operation status is returned in
+// the Operation of the Response.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/delete-docpage/
+func (s *ResourcesService) Delete(
+	ctx context.Context,
+	path Path,
+	opt *DeleteOptions,
+) (*Response, error) {
+	params, err := query.Values(opt)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("path", string(path))
+
+	url := "disk/resources"
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.delete"),
+		"DELETE",
+		url,
+		nil,
+		WithQuery(params),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Do(req, nil)
+}
+
+// API error codes for creating an existing folder or file,
+// and for a missing parent folder.
+const (
+	errCodeDirExists      = "DiskPathPointsToExistentDirectoryError"
+	errCodeResourceExists = "DiskResourceAlreadyExistsError"
+	errCodeParentMissing  = "DiskPathDoesntExistsError"
+)
+
+// Mkdir creates the folder at the path. The parent folder
+// must exist. The link to the created folder is returned.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/create-folder-docpage/
+func (s *ResourcesService) Mkdir(ctx context.Context, path Path) (*Link, *Response, error) {
+	params := url.Values{"path": {string(path)}}
+
+	url := "disk/resources"
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.mkdir"),
+		"PUT",
+		url,
+		nil,
+		WithQuery(params),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link := new(Link)
+	resp, err := s.client.Do(req, link)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return link, resp, nil
+}
+
+// MkdirAll creates the folder at the path along with the missing
+// parent folders. The existing folders are left as is.
+func (s *ResourcesService) MkdirAll(ctx context.Context, path Path) error {
+	ns, rel := path.split()
+	var dir Path
+	if ns != "" {
+		dir = Path(ns + ":")
+	}
+	for _, name := range strings.Split(rel, "/") {
+		if name == "" {
+			continue
+		}
+		dir += Path("/" + name)
+		_, _, err := s.Mkdir(ctx, dir)
+		if err != nil && !hasErrorCode(err, errCodeDirExists) {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveOptions specifies the optional parameters to the
+// ResourcesService.Move method.
+type MoveOptions struct {
+	// Overwrite the existing file or folder at the destination path.
+	Overwrite bool `url:"overwrite,omitempty"`
+}
+
+// Move moves or renames the file or folder at the from path to the
+// path. The link to the moved resource is returned. Folders may be
+// moved asynchronously, the link to the operation status is returned
+// in the Operation of the Response then, see WaitOperation.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/move-docpage/
+func (s *ResourcesService) Move(
+	ctx context.Context,
+	from Path,
+	path Path,
+	opt *MoveOptions,
+) (*Link, *Response, error) {
+	return s.relocate(WithOperation(ctx, "resources.move"), "disk/resources/move", from, path, opt)
+}
+
+// CopyOptions specifies the optional parameters to the
+// ResourcesService.Copy method.
+type CopyOptions struct {
+	// Overwrite the existing file or folder at the destination path.
+	Overwrite bool `url:"overwrite,omitempty"`
+}
+
+// Copy copies the file or folder at the from path to the path.
+// The link to the copy is returned. Folders may be copied
+// asynchronously, the link to the operation status is returned
+// in the Operation of the Response then, see WaitOperation.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/copy-docpage/
+func (s *ResourcesService) Copy(
+	ctx context.Context,
+	from Path,
+	path Path,
+	opt *CopyOptions,
+) (*Link, *Response, error) {
+	return s.relocate(WithOperation(ctx, "resources.copy"), "disk/resources/copy", from, path, opt)
+}
+
+// relocate sends the move or copy request with the options opt.
+func (s *ResourcesService) relocate(
+	ctx context.Context,
+	url string,
+	from Path,
+	path Path,
+	opt interface{},
+) (*Link, *Response, error) {
+	params, err := query.Values(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.Set("from", string(from))
+	params.Set("path", string(path))
+
+	req, err := s.client.NewRequestWithContext(ctx, "POST", url, nil, WithQuery(params))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link := new(Link)
+	resp, err := s.client.Do(req, link)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return link, resp, nil
+}
+
+// ErrOperationFailed is returned by WaitOperation
+// when the asynchronous operation fails.
+var ErrOperationFailed = errors.New("yadisk: operation failed")
+
+// operationPollInterval is the delay between the requests
+// for the status of an asynchronous operation.
+var operationPollInterval = 500 * time.Millisecond
+
+// WaitOperation waits for the asynchronous operation started by the
+// request with the response resp to finish, polling its status. It
+// returns right away if the response has no Operation link.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/operations-docpage/
+func (s *ResourcesService) WaitOperation(ctx context.Context, resp *Response) error {
+	if resp == nil || resp.Operation == nil {
+		return nil
+	}
+	for {
+		var status struct {
+			Status string `json:"status"`
+		}
+		if _, err := s.client.Follow(ctx, resp.Operation, &status); err != nil {
+			return err
+		}
+		switch status.Status {
+		case "success":
+			return nil
+		case "failed":
+			return ErrOperationFailed
+		}
+
+		timer := time.NewTimer(operationPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// listPageSize is the number of resources requested
+// with a single folder listing request.
+const listPageSize = 100
+
+// ListOptions specifies the optional parameters to the
+// ResourcesService.List method.
+type ListOptions struct {
+	// The sort key of the resources, like for Get.
+	Sort string
+
+	// List of JSON keys of the resources that should be included
+	// in the response, like "name" or "size". Empty means all.
+	Fields []string
+}
+
+// List returns all the resources in the folder, requesting them
+// page by page with Get, or with GetTrash for the "trash:" paths.
+// ErrNotFolder is returned for a file.
+func (s *ResourcesService) List(ctx context.Context, dir Path, opt *ListOptions) ([]Resource, error) {
+	if opt == nil {
+		opt = new(ListOptions)
+	}
+
+	var fields []string
+	if len(opt.Fields) > 0 {
+		fields = make([]string, 0, len(opt.Fields)+1)
+		for _, f := range opt.Fields {
+			fields = append(fields, "_embedded.items."+f)
+		}
+		fields = append(fields, "_embedded.total")
+	}
+
+	var items []Resource
+	for {
+		resource, err := s.getAny(ctx, dir, &ResourcesOptions{
+			Sort:   opt.Sort,
+			Fields: fields,
+			Limit:  listPageSize,
+			Offset: uint(len(items)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resource.Embedded == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotFolder, dir)
+		}
+		items = append(items, resource.Embedded.Items...)
+		if len(resource.Embedded.Items) == 0 || uint(len(items)) >= resource.Embedded.Total {
+			return items, nil
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package yadisk
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Rate represents the rate limit state reported by the API.
+// The fields are zero if the corresponding headers are missing.
+type Rate struct {
+	// The number of requests allowed in the current period.
+	Limit int
+
+	// The number of requests remaining in the current period.
+	Remaining int
+
+	// The time at which the current period resets.
+	Reset time.Time
+
+	// The time the API asked to wait before making a new request.
+	RetryAfter time.Duration
+}
+
+// Response is a Yandex.Disk API response. It wraps the standard
+// http.Response, whose body is already drained and closed,
+// and provides the parsed response metadata.
+type Response struct {
+	*http.Response
+
+	// RequestID is the request identifier assigned by Yandex.
+	// Mention it when contacting the Yandex.Disk support.
+	RequestID string
+
+	// Rate is the rate limit state reported in the response headers.
+	Rate Rate
+
+	// Operation is the link for checking the status of
+	// the asynchronous operation. It is set only when the API
+	// responds with 202 Accepted.
+	Operation *Link
+
+	// Retries is the number of retries performed
+	// before the response was received.
+	Retries int
+
+	// Latency is the total time spent on the request, including retries.
+	Latency time.Duration
+}
+
+// newResponse creates a new Response for the provided http.Response.
+func newResponse(r *http.Response) *Response {
+	response := &Response{
+		Response:  r,
+		RequestID: r.Header.Get("Yandex-Cloud-Request-ID"),
+	}
+	if response.RequestID == "" {
+		response.RequestID = r.Header.Get("X-Request-Id")
+	}
+	response.Rate = parseRate(r)
+	return response
+}
+
+// parseRate parses the rate limit headers of the response.
+func parseRate(r *http.Response) Rate {
+	var rate Rate
+	if limit := r.Header.Get("X-RateLimit-Limit"); limit != "" {
+		rate.Limit, _ = strconv.Atoi(limit)
+	}
+	if remaining := r.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+		rate.Remaining, _ = strconv.Atoi(remaining)
+	}
+	if reset := r.Header.Get("X-RateLimit-Reset"); reset != "" {
+		if v, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			rate.Reset = time.Unix(v, 0)
+		}
+	}
+	if retryAfter := r.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			rate.RetryAfter = time.Duration(seconds) * time.Second
+		} else if t, err := http.ParseTime(retryAfter); err == nil {
+			rate.RetryAfter = time.Until(t)
+		}
+	}
+	return rate
+}
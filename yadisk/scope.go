@@ -0,0 +1,172 @@
+package yadisk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrOutOfScope is returned by the scoped clients for the requests
+// which would leave the application folder.
+var ErrOutOfScope = errors.New("yadisk: path is out of the application folder")
+
+// AppFolder returns a copy of the client scoped to the application
+// folder, for the tokens with the app folder access only. The copy
+// shares the HTTP client and the other settings with c.
+//
+// The paths given to the scoped client are relative to the
+// application folder: "/cache/a.txt" and "app:/cache/a.txt" both
+// refer to "app:/cache/a.txt". The paths of the returned resources
+// are stripped the same way, so they can be passed back as is.
+// The paths in the other namespaces, the ".." elements leaving the
+// application folder and the Trash requests fail with ErrOutOfScope.
+//
+// Disk may return the paths of the application folder resources as
+// the paths within the Applications system folder, like
+// "disk:/Applications/MyApp/cache/a.txt". To strip them, the
+// SystemFolders are requested once, on the first such path.
+func (c *Client) AppFolder() *Client {
+	scoped := *c
+	scoped.scope = new(appScope)
+	scoped.Disk = &DiskService{client: &scoped}
+	scoped.Resources = &ResourcesService{client: &scoped}
+	return &scoped
+}
+
+// appScope keeps the state of a client scoped to the application folder.
+type appScope struct {
+	// The Applications system folder, requested on demand.
+	mu   sync.Mutex
+	apps Path
+}
+
+// rewrite rewrites the paths in the query of the API request
+// into the application folder.
+func (s *appScope) rewrite(c *Client, req *http.Request) error {
+	endpoint, ok := c.endpoint(req)
+	if !ok {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(endpoint, "disk/trash/"):
+		return fmt.Errorf("%w: %s", ErrOutOfScope, endpoint)
+	case !strings.HasPrefix(endpoint, "disk/resources"):
+		// The public resources and the Disk information
+		// don't refer to the Disk paths.
+		return nil
+	}
+
+	q := req.URL.Query()
+	for _, key := range []string{"path", "from"} {
+		if _, ok := q[key]; !ok {
+			continue
+		}
+		p, err := scopedPath(Path(q.Get(key)))
+		if err != nil {
+			return err
+		}
+		q.Set(key, string(p))
+	}
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// scopedPath returns the path within the application folder.
+func scopedPath(p Path) (Path, error) {
+	ns, rel := p.split()
+	if ns != "" && ns != NamespaceApp {
+		return "", fmt.Errorf("%w: %q", ErrOutOfScope, p)
+	}
+	depth := 0
+	for _, name := range strings.Split(rel, "/") {
+		switch name {
+		case "", ".":
+		case "..":
+			if depth--; depth < 0 {
+				return "", fmt.Errorf("%w: %q", ErrOutOfScope, p)
+			}
+		default:
+			depth++
+		}
+	}
+	return Path(string(NamespaceApp) + ":" + p.Rel()), nil
+}
+
+// unscope strips the application folder from the paths
+// of the resources decoded into v.
+func (s *appScope) unscope(ctx context.Context, c *Client, v interface{}) error {
+	switch v := v.(type) {
+	case *Resource:
+		return s.unscopeResource(ctx, c, v)
+	case *ResourceList:
+		return s.unscopeList(ctx, c, v)
+	}
+	return nil
+}
+
+func (s *appScope) unscopeResource(ctx context.Context, c *Client, r *Resource) error {
+	var err error
+	if r.Path, err = s.unscopePath(ctx, c, r.Path); err != nil {
+		return err
+	}
+	if r.Embedded != nil {
+		return s.unscopeList(ctx, c, r.Embedded)
+	}
+	return nil
+}
+
+func (s *appScope) unscopeList(ctx context.Context, c *Client, l *ResourceList) error {
+	var err error
+	if l.Path, err = s.unscopePath(ctx, c, l.Path); err != nil {
+		return err
+	}
+	for i := range l.Items {
+		if err := s.unscopeResource(ctx, c, &l.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unscopePath returns the path relative to the application folder.
+func (s *appScope) unscopePath(ctx context.Context, c *Client, p Path) (Path, error) {
+	switch ns, _ := p.split(); ns {
+	case "":
+		return p, nil
+	case NamespaceApp:
+		return Path(p.Rel()), nil
+	case NamespaceDisk:
+		apps, err := s.applications(ctx, c)
+		if err != nil {
+			return "", err
+		}
+		if apps.IsRoot() || !p.HasPrefix(apps) || p.Equal(apps) {
+			return "", fmt.Errorf("%w: %q", ErrOutOfScope, p)
+		}
+		// Skip the folder of the application in the Applications folder.
+		rel := strings.TrimPrefix(strings.TrimPrefix(p.Rel(), apps.Rel()), "/")
+		if i := strings.Index(rel, "/"); i >= 0 {
+			return Path(rel[i:]), nil
+		}
+		return "/", nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrOutOfScope, p)
+}
+
+// applications returns the path of the Applications system folder.
+func (s *appScope) applications(ctx context.Context, c *Client) (Path, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.apps != "" {
+		return s.apps, nil
+	}
+	disk, _, err := c.Disk.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.apps = Path(disk.SystemFolders.Applications)
+	return s.apps, nil
+}
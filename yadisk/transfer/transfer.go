@@ -0,0 +1,569 @@
+// Package transfer provides a queue of uploads and downloads
+// which survives process restarts.
+//
+// The state of every transfer is recorded in a journal, a local file
+// with a JSON object per line. When the queue is opened again, the
+// transfers which haven't finished are run again: uploads continue
+// with the saved upload link, downloads continue from the saved
+// download state. Usage:
+//
+//	queue, err := transfer.Open(client, "transfers.jsonl", nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer queue.Close()
+//
+//	queue.Upload("dump.sql", "/backup/dump.sql")
+//	err = queue.Run(ctx)
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+)
+
+// defaultConcurrency is the number of transfers run
+// in parallel if not set in the options.
+const defaultConcurrency = 2
+
+// ErrNotFound is returned for an unknown transfer ID.
+var ErrNotFound = errors.New("transfer: not found")
+
+// Kind is the direction of a transfer.
+type Kind string
+
+const (
+	// Upload sends a local file to Disk.
+	Upload Kind = "upload"
+
+	// Download saves a file on Disk locally.
+	Download Kind = "download"
+)
+
+// State is the state of a transfer.
+type State string
+
+const (
+	// Queued transfers wait to be run.
+	Queued State = "queued"
+
+	// Running transfers are being run.
+	Running State = "running"
+
+	// Done transfers have finished successfully.
+	Done State = "done"
+
+	// Failed transfers have failed MaxAttempts times.
+	Failed State = "failed"
+)
+
+// Item is a single transfer in the queue.
+type Item struct {
+	ID   int64 `json:"id"`
+	Kind Kind  `json:"kind"`
+
+	// Path to the local file and to the file on Disk.
+	LocalPath string      `json:"local_path"`
+	Path      yadisk.Path `json:"path"`
+
+	State State `json:"state"`
+
+	// The error of the last failed attempt.
+	Error string `json:"error,omitempty"`
+
+	// The number of times the transfer has been started.
+	Attempts int `json:"attempts"`
+
+	// The upload link of an unfinished upload.
+	UploadLink *yadisk.Link `json:"upload_link,omitempty"`
+
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Stats is the number of transfers in every state.
+type Stats struct {
+	Queued  int
+	Running int
+	Done    int
+	Failed  int
+}
+
+// Options specifies the optional parameters to Open.
+type Options struct {
+	// The number of transfers run in parallel. Defaults to 2.
+	Concurrency int
+
+	// The number of times a failed transfer is started before it's
+	// marked as failed. Every attempt resumes the transfer where
+	// the previous one stopped. Defaults to 1.
+	MaxAttempts int
+
+	// The options of the uploads and downloads. The resume
+	// related fields are set by the queue. Like UploadFile and
+	// DownloadFile, the transfers are verified unless their
+	// Verify option is VerifyNever.
+	Upload   yadisk.ResumableUploadOptions
+	Download yadisk.DownloadOptions
+}
+
+// Queue is a persistent queue of transfers. Its methods are safe
+// for concurrent use, transfers can be added while the queue is running.
+type Queue struct {
+	client      *yadisk.Client
+	opt         Options
+	journalPath string
+
+	mu      sync.Mutex
+	journal *os.File
+	items   map[int64]*Item
+	order   []int64
+	nextID  int64
+	running bool
+	err     error
+	changed chan struct{}
+
+	// The number of lines appended to the journal since it was compacted.
+	appended int
+}
+
+// Open opens the queue with the journal at the path, creating the
+// journal if it doesn't exist. Transfers which were running when
+// the journal was last written are queued again. The journal is
+// compacted to the latest state of every transfer.
+func Open(client *yadisk.Client, journalPath string, opt *Options) (*Queue, error) {
+	q := &Queue{
+		client:      client,
+		journalPath: journalPath,
+		items:       make(map[int64]*Item),
+		nextID:      1,
+		changed:     make(chan struct{}, 1),
+	}
+	if opt != nil {
+		q.opt = *opt
+	}
+	if q.opt.Concurrency <= 0 {
+		q.opt.Concurrency = defaultConcurrency
+	}
+	if q.opt.MaxAttempts <= 0 {
+		q.opt.MaxAttempts = 1
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// load replays the journal.
+func (q *Queue) load() error {
+	f, err := os.Open(q.journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		item := new(Item)
+		if err := json.Unmarshal(scanner.Bytes(), item); err != nil {
+			// The last line may be cut off by a crash while writing it.
+			if !scanner.Scan() {
+				break
+			}
+			return fmt.Errorf("transfer: journal line %d: %v", line, err)
+		}
+		if _, ok := q.items[item.ID]; !ok {
+			q.order = append(q.order, item.ID)
+		}
+		if item.State == Running {
+			item.State = Queued
+		}
+		q.items[item.ID] = item
+		if item.ID >= q.nextID {
+			q.nextID = item.ID + 1
+		}
+	}
+	return scanner.Err()
+}
+
+// compactMinLines is the minimal number of the lines appended to the
+// journal before it's compacted while running. The journal is compacted
+// once the appended lines outnumber the transfers too.
+const compactMinLines = 1000
+
+// compact atomically rewrites the journal with the current
+// state of the transfers and opens it for appending.
+func (q *Queue) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(q.journalPath), filepath.Base(q.journalPath)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, id := range q.order {
+		if err := enc.Encode(q.items[id]); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), q.journalPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	journal, err := os.OpenFile(q.journalPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if q.journal != nil {
+		q.journal.Close()
+	}
+	q.journal = journal
+	q.appended = 0
+	return nil
+}
+
+// shouldCompact reports whether the journal has grown enough
+// to be compacted while running. It must be called with the mutex held.
+func (q *Queue) shouldCompact() bool {
+	return q.appended >= compactMinLines && q.appended > len(q.order)
+}
+
+// Close closes the journal. Running transfers should be
+// stopped by canceling the Run context first.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.journal.Close()
+}
+
+// Upload queues the upload of the local file to the path
+// and returns the ID of the transfer.
+func (q *Queue) Upload(localPath string, path yadisk.Path) (int64, error) {
+	return q.add(Upload, localPath, path)
+}
+
+// Download queues the download of the file at the path
+// to the local file and returns the ID of the transfer.
+func (q *Queue) Download(path yadisk.Path, localPath string) (int64, error) {
+	return q.add(Download, localPath, path)
+}
+
+func (q *Queue) add(kind Kind, localPath string, path yadisk.Path) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	item := &Item{
+		ID:        q.nextID,
+		Kind:      kind,
+		LocalPath: localPath,
+		Path:      path,
+		State:     Queued,
+		Created:   now,
+		Updated:   now,
+	}
+	if err := q.write(item); err != nil {
+		return 0, err
+	}
+	q.nextID++
+	q.items[item.ID] = item
+	q.order = append(q.order, item.ID)
+	q.notify()
+	return item.ID, nil
+}
+
+// Get returns the transfer with the ID.
+func (q *Queue) Get(id int64) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[id]
+	if !ok {
+		return Item{}, ErrNotFound
+	}
+	return *item, nil
+}
+
+// List returns all the transfers in the order they were added.
+func (q *Queue) List() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := make([]Item, 0, len(q.order))
+	for _, id := range q.order {
+		items = append(items, *q.items[id])
+	}
+	return items
+}
+
+// Stats returns the number of transfers in every state.
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var stats Stats
+	for _, item := range q.items {
+		switch item.State {
+		case Queued:
+			stats.Queued++
+		case Running:
+			stats.Running++
+		case Done:
+			stats.Done++
+		case Failed:
+			stats.Failed++
+		}
+	}
+	return stats
+}
+
+// Retry queues the failed transfer again.
+func (q *Queue) Retry(id int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.items[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if item.State != Failed {
+		return fmt.Errorf("transfer: %d is %s, not failed", id, item.State)
+	}
+	if err := q.update(item, func(item *Item) {
+		item.State = Queued
+		item.Attempts = 0
+	}); err != nil {
+		return err
+	}
+	q.notify()
+	return nil
+}
+
+// Run runs the queued transfers, at most Concurrency at once,
+// until all of them have finished or the context is done.
+// Transfers added while running are run too. The failed transfers
+// are listed with the Failed state, they don't stop the queue.
+//
+// When the context is done, the running transfers are stopped and
+// left queued to be resumed by the next Run. Only one Run may be
+// active at a time. The journal is compacted while running
+// and when Run returns.
+func (q *Queue) Run(ctx context.Context) (err error) {
+	q.mu.Lock()
+	if q.running {
+		q.mu.Unlock()
+		return errors.New("transfer: queue is already running")
+	}
+	q.running = true
+	q.mu.Unlock()
+
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		q.mu.Lock()
+		q.running = false
+		if q.err == nil && q.appended > 0 {
+			if compactErr := q.compact(); err == nil {
+				err = compactErr
+			}
+		}
+		q.mu.Unlock()
+	}()
+
+	active := 0
+	done := make(chan struct{}, q.opt.Concurrency)
+	for {
+		q.mu.Lock()
+		if q.err != nil {
+			err := q.err
+			q.mu.Unlock()
+			return err
+		}
+		if q.shouldCompact() {
+			if err := q.compact(); err != nil {
+				q.mu.Unlock()
+				return err
+			}
+		}
+		for _, id := range q.order {
+			if active >= q.opt.Concurrency {
+				break
+			}
+			item := q.items[id]
+			if item.State != Queued {
+				continue
+			}
+			if err := q.update(item, func(item *Item) {
+				item.State = Running
+				item.Attempts++
+			}); err != nil {
+				q.mu.Unlock()
+				return err
+			}
+			active++
+			wg.Add(1)
+			go func(item Item) {
+				defer wg.Done()
+				q.finish(ctx, item.ID, q.transfer(ctx, item))
+				done <- struct{}{}
+			}(*item)
+		}
+		q.mu.Unlock()
+
+		if active == 0 {
+			return nil
+		}
+		select {
+		case <-done:
+			active--
+		case <-q.changed:
+		case <-ctx.Done():
+			// Let the transfers stop.
+			for ; active > 0; active-- {
+				<-done
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// transfer runs a single attempt of the transfer.
+func (q *Queue) transfer(ctx context.Context, item Item) error {
+	switch item.Kind {
+	case Upload:
+		return q.upload(ctx, item)
+	case Download:
+		opt := q.opt.Download
+		opt.StatePath = q.statePath(item.ID)
+		_, err := q.client.Resources.DownloadFile(ctx, item.Path, item.LocalPath, &opt)
+		return err
+	}
+	return fmt.Errorf("transfer: unknown kind %q", item.Kind)
+}
+
+func (q *Queue) upload(ctx context.Context, item Item) error {
+	f, err := os.Open(item.LocalPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	opt := q.opt.Upload
+	if opt.Verify == yadisk.VerifyAuto {
+		// The local file is uploaded like with UploadFile.
+		opt.Verify = yadisk.VerifyAlways
+	}
+	opt.Link = item.UploadLink
+	opt.OnLink = func(link *yadisk.Link) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.update(q.items[item.ID], func(item *Item) {
+			item.UploadLink = link
+		})
+	}
+	_, err = q.client.Resources.UploadResumable(ctx, item.Path, f, info.Size(), &opt)
+	return err
+}
+
+// finish records the result of the transfer attempt.
+func (q *Queue) finish(ctx context.Context, id int64, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.update(q.items[id], func(item *Item) {
+		switch {
+		case err == nil:
+			item.State = Done
+			item.Error = ""
+			item.UploadLink = nil
+		case ctx.Err() != nil:
+			// Stopped, resume later.
+			item.State = Queued
+			item.Attempts--
+		case item.Attempts < q.opt.MaxAttempts:
+			item.State = Queued
+			item.Error = err.Error()
+		default:
+			item.State = Failed
+			item.Error = err.Error()
+		}
+	})
+}
+
+// update changes the transfer and records it in the journal.
+// It must be called with the mutex held. A journal error is kept
+// and stops the queue.
+func (q *Queue) update(item *Item, change func(item *Item)) error {
+	updated := *item
+	change(&updated)
+	updated.Updated = time.Now()
+	if err := q.write(&updated); err != nil {
+		if q.err == nil {
+			q.err = err
+		}
+		return err
+	}
+	*item = updated
+	return nil
+}
+
+// write appends the transfer state to the journal.
+// It must be called with the mutex held.
+func (q *Queue) write(item *Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if _, err := q.journal.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	q.appended++
+	return q.journal.Sync()
+}
+
+// notify wakes Run up to start the new transfers.
+func (q *Queue) notify() {
+	select {
+	case q.changed <- struct{}{}:
+	default:
+	}
+}
+
+// statePath returns the path to the download state file
+// of the transfer, next to the journal.
+func (q *Queue) statePath(id int64) string {
+	return fmt.Sprintf("%s.%d.download", q.journalPath, id)
+}
@@ -0,0 +1,558 @@
+package yadisk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+// defaultUploadRetries is the number of retries of a failed
+// resumable upload attempt if not set in the options.
+const defaultUploadRetries = 5
+
+// UploadOptions specifies the optional parameters to the
+// ResourcesService.GetUploadLink and ResourcesService.Upload methods.
+type UploadOptions struct {
+	// Overwrite the file if it already exists.
+	Overwrite bool `url:"overwrite,omitempty"`
+
+	// List of JSON keys that should be included in the response.
+	Fields []string `url:"fields,comma,omitempty"`
+
+	// Progress, if set, is called with the upload progress.
+	Progress ProgressFunc `url:"-"`
+
+	// The minimal time between progress reports. Defaults to 500ms.
+	ProgressInterval time.Duration `url:"-"`
+
+	// Bandwidth, if set, limits the upload rate in addition
+	// to the Client Bandwidth.
+	Bandwidth *BandwidthLimiter `url:"-"`
+
+	// Verify controls whether the MD5 and SHA-256 hashes of the
+	// uploaded data are compared to the ones of the uploaded file.
+	// The hashes are computed while uploading. On mismatch
+	// a *ChecksumError is returned.
+	Verify VerifyMode `url:"-"`
+
+	// Delete the uploaded file permanently if the verification fails.
+	DeleteOnMismatch bool `url:"-"`
+}
+
+// ResumableUploadOptions specifies the optional parameters to the
+// ResourcesService.UploadResumable method.
+type ResumableUploadOptions struct {
+	UploadOptions
+
+	// The number of bytes sent in a single request. Zero means
+	// the whole remaining data is sent in one request.
+	ChunkSize int64 `url:"-"`
+
+	// The number of times a failed request is retried
+	// without any progress being made. Defaults to 5.
+	MaxRetries int `url:"-"`
+
+	// Link, if set, is the upload link of an earlier upload
+	// of the same file to continue, like one saved by OnLink before
+	// the process was restarted. The uploader is asked for the upload
+	// state first. If the link has expired, a new one is requested.
+	Link *Link `url:"-"`
+
+	// OnLink, if set, is called with every new upload link.
+	OnLink func(link *Link) `url:"-"`
+}
+
+// GetUploadLink requests the URL for uploading a file to the path.
+// The link is valid for 30 minutes.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/upload-docpage/
+func (s *ResourcesService) GetUploadLink(
+	ctx context.Context,
+	path Path,
+	opt *UploadOptions,
+) (*Link, *Response, error) {
+	params, err := query.Values(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.Set("path", string(path))
+
+	url := "disk/resources/upload"
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.upload_link"),
+		"GET",
+		url,
+		nil,
+		WithQuery(params),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link := new(Link)
+	resp, err := s.client.Do(req, link)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return link, resp, nil
+}
+
+// Upload uploads the data read from body to the file at the path.
+// The upload link is requested with GetUploadLink. The total size
+// for the progress reports is known if body has the Len method,
+// like bytes.Reader.
+//
+// Yandex.Disk API docs: https://tech.yandex.com/disk/api/reference/upload-docpage/
+func (s *ResourcesService) Upload(
+	ctx context.Context,
+	path Path,
+	body io.Reader,
+	opt *UploadOptions,
+) (*Response, error) {
+	link, resp, err := s.GetUploadLink(ctx, path, opt)
+	if err != nil {
+		return resp, err
+	}
+	if opt == nil {
+		opt = new(UploadOptions)
+	}
+	return s.uploadTo(ctx, path, link, body, opt)
+}
+
+// uploadTo sends the body to the upload link in a single request
+// and verifies the upload if requested.
+func (s *ResourcesService) uploadTo(
+	ctx context.Context,
+	path Path,
+	link *Link,
+	body io.Reader,
+	opt *UploadOptions,
+) (*Response, error) {
+	size := readerSize(body)
+	var sums *checksums
+	if opt.Verify.enabled(false) {
+		sums = newChecksums()
+		body = &checksumReader{r: body, checksums: sums}
+	}
+	body = s.client.throttle(ctx, body, opt.Bandwidth)
+	if opt.Progress != nil {
+		tracker := newProgressTracker(opt.Progress, opt.ProgressInterval, size)
+		defer tracker.finish()
+		body = &progressReader{r: body, tracker: tracker}
+	}
+
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.upload"),
+		uploadMethod(link),
+		link.Href,
+		body,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+
+	resp, err := s.client.Do(req, nil)
+	if err != nil || sums == nil {
+		return resp, err
+	}
+	return resp, s.verifyUpload(ctx, path, sums, opt)
+}
+
+// UploadResumable uploads size bytes read from src to the file at
+// the path. Unlike Upload, it survives network failures: after a failed
+// request it asks the uploader how many bytes have been accepted and
+// continues from there with the Content-Range header, waiting between
+// attempts. If the upload link has expired, a new one is requested.
+//
+// The uploader is asked for the upload state with an empty PUT request
+// with the "Content-Range: bytes */<size>" header. It responds with
+// 308 and the "Range: bytes=0-<last byte>" header for an incomplete upload,
+// and with 200 or 201 for a complete one.
+//
+// Use SeekReaderAt to upload from an io.ReadSeeker.
+func (s *ResourcesService) UploadResumable(
+	ctx context.Context,
+	path Path,
+	src io.ReaderAt,
+	size int64,
+	opt *ResumableUploadOptions,
+) (*Response, error) {
+	if opt == nil {
+		opt = new(ResumableUploadOptions)
+	}
+	return s.uploadResumable(ctx, path, src, size, opt, false)
+}
+
+// UploadFile uploads the local file to the path with UploadResumable.
+// The upload is verified unless the Verify option is VerifyNever.
+func (s *ResourcesService) UploadFile(
+	ctx context.Context,
+	localPath string,
+	path Path,
+	opt *UploadOptions,
+) (*Response, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	resumableOpt := new(ResumableUploadOptions)
+	if opt != nil {
+		resumableOpt.UploadOptions = *opt
+	}
+	return s.uploadResumable(ctx, path, f, info.Size(), resumableOpt, true)
+}
+
+// uploadResumable uploads the file and verifies the upload.
+func (s *ResourcesService) uploadResumable(
+	ctx context.Context,
+	path Path,
+	src io.ReaderAt,
+	size int64,
+	opt *ResumableUploadOptions,
+	fileToFile bool,
+) (*Response, error) {
+	var sums *checksums
+	if opt.Verify.enabled(fileToFile) {
+		sums = newChecksums()
+	}
+
+	resp, err := s.sendResumable(ctx, path, src, size, opt, sums)
+	if err != nil || sums == nil {
+		return resp, err
+	}
+
+	// Hash the parts the uploader had before the upload was resumed.
+	if err := sums.readFrom(src, size); err != nil {
+		return resp, err
+	}
+	return resp, s.verifyUpload(ctx, path, sums, &opt.UploadOptions)
+}
+
+// verifyUpload compares the hashes of the uploaded data
+// with the ones of the uploaded file.
+func (s *ResourcesService) verifyUpload(
+	ctx context.Context,
+	path Path,
+	sums *checksums,
+	opt *UploadOptions,
+) error {
+	resource, _, err := s.Get(ctx, path, &ResourcesOptions{
+		Fields: []string{"path", "md5", "sha256"},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = sums.verify(path, resource)
+	var checksumErr *ChecksumError
+	if errors.As(err, &checksumErr) && opt.DeleteOnMismatch {
+		_, checksumErr.DeleteErr = s.Delete(ctx, path, &DeleteOptions{Permanently: true})
+	}
+	return err
+}
+
+// sendResumable sends the file to the uploader, resuming after
+// failures. The sent data is hashed into sums if it's not nil.
+func (s *ResourcesService) sendResumable(
+	ctx context.Context,
+	path Path,
+	src io.ReaderAt,
+	size int64,
+	opt *ResumableUploadOptions,
+	sums *checksums,
+) (*Response, error) {
+	maxRetries := opt.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultUploadRetries
+	}
+
+	tracker := newProgressTracker(opt.Progress, opt.ProgressInterval, size)
+	defer tracker.finish()
+
+	var (
+		link     = opt.Link
+		offset   int64
+		resume   = opt.Link != nil
+		failures int
+		resp     *Response
+		err      error
+	)
+	setOffset := func(n int64) {
+		offset = n
+		tracker.set(n)
+	}
+	for {
+		if err != nil {
+			failures++
+			if failures > maxRetries || !s.client.isRetryableUploadError(ctx, resp) {
+				return resp, err
+			}
+			delay := retryDelay(failures-1, resp)
+			if delay > maxRetryDelay {
+				// Like Client.Do, don't keep the caller waiting for long.
+				return resp, err
+			}
+			select {
+			case <-ctx.Done():
+				return resp, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		// Request a new link if there is none or the old one has expired.
+		// The upload starts from scratch with every new link.
+		if link == nil {
+			link, resp, err = s.GetUploadLink(ctx, path, &opt.UploadOptions)
+			if err != nil {
+				link = nil
+				continue
+			}
+			if opt.OnLink != nil {
+				opt.OnLink(link)
+			}
+			resume = false
+			setOffset(0)
+		}
+
+		// Ask the uploader how much has been accepted after a failure.
+		if resume {
+			var (
+				accepted int64
+				complete bool
+			)
+			accepted, complete, resp, err = s.uploadState(ctx, link, size)
+			if err != nil {
+				if s.client.isLinkExpired(resp) {
+					link = nil
+				}
+				continue
+			}
+			if accepted > offset {
+				failures = 0
+			}
+			resume = false
+			setOffset(accepted)
+			if complete {
+				return resp, nil
+			}
+		}
+
+		// Send the next part of the file.
+		end := size
+		if opt.ChunkSize > 0 && offset+opt.ChunkSize < size {
+			end = offset + opt.ChunkSize
+		}
+		var complete bool
+		resp, complete, err = s.uploadChunk(ctx, link, src, offset, end, size, opt.Bandwidth, tracker, sums)
+		if err != nil {
+			if s.client.isLinkExpired(resp) {
+				link = nil
+			} else {
+				resume = true
+			}
+			continue
+		}
+		failures = 0
+		setOffset(end)
+		if complete {
+			return resp, nil
+		}
+		// Everything has been sent but the uploader
+		// hasn't confirmed it, so ask for the state.
+		resume = offset == size
+	}
+}
+
+// uploadState asks the uploader how many bytes of the file have been
+// accepted and whether the upload is complete.
+func (s *ResourcesService) uploadState(
+	ctx context.Context,
+	link *Link,
+	size int64,
+) (int64, bool, *Response, error) {
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.upload_state"),
+		uploadMethod(link),
+		link.Href,
+		nil,
+	)
+	if err != nil {
+		return 0, false, nil, err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+
+	resp, err := s.client.Do(req, nil)
+	if err != nil {
+		return 0, false, resp, err
+	}
+	if resp.StatusCode != http.StatusPermanentRedirect {
+		return size, true, resp, nil
+	}
+
+	accepted, err := parseAcceptedRange(resp.Header.Get("Range"))
+	if err != nil {
+		return 0, false, resp, err
+	}
+	return accepted, false, resp, nil
+}
+
+// uploadChunk sends the bytes from start to end of the file.
+func (s *ResourcesService) uploadChunk(
+	ctx context.Context,
+	link *Link,
+	src io.ReaderAt,
+	start, end, size int64,
+	bandwidth *BandwidthLimiter,
+	tracker *progressTracker,
+	sums *checksums,
+) (*Response, bool, error) {
+	var body io.Reader = io.NewSectionReader(src, start, end-start)
+	if sums != nil {
+		body = &checksumSectionReader{r: body, offset: start, checksums: sums}
+	}
+	body = s.client.throttle(ctx, body, bandwidth)
+	if tracker != nil {
+		body = &progressReader{r: body, tracker: tracker}
+	}
+
+	req, err := s.client.NewRequestWithContext(
+		WithOperation(ctx, "resources.upload"),
+		uploadMethod(link),
+		link.Href,
+		body,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	req.ContentLength = end - start
+	if start > 0 || end < size {
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+	}
+
+	resp, err := s.client.Do(req, nil)
+	if err != nil {
+		return resp, false, err
+	}
+	return resp, resp.StatusCode != http.StatusPermanentRedirect, nil
+}
+
+// parseAcceptedRange returns the number of accepted bytes from
+// the Range header value like "bytes=0-1023".
+func parseAcceptedRange(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+	spec := strings.TrimPrefix(header, "bytes=")
+	i := strings.IndexByte(spec, '-')
+	if i < 0 || spec[:i] != "0" {
+		return 0, fmt.Errorf("yadisk: invalid Range header %q", header)
+	}
+	last, err := strconv.ParseInt(spec[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("yadisk: invalid Range header %q", header)
+	}
+	return last + 1, nil
+}
+
+// isRetryableUploadError reports whether the upload that failed
+// with the response can be continued.
+func (c *Client) isRetryableUploadError(ctx context.Context, resp *Response) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if resp == nil {
+		return true
+	}
+	return c.isLinkExpired(resp) ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500
+}
+
+// isLinkExpired reports whether the uploader or the downloader has
+// rejected the link: with 410 Gone, or with 404 Not Found from a host
+// other than the API, which responds so for the missing resources.
+func (c *Client) isLinkExpired(resp *Response) bool {
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusGone:
+		return true
+	case http.StatusNotFound:
+		if resp.Request == nil {
+			return false
+		}
+		_, isAPI := c.endpoint(resp.Request)
+		return !isAPI
+	}
+	return false
+}
+
+// uploadMethod returns the HTTP method for uploading to the link.
+func uploadMethod(link *Link) string {
+	if link.Method == "" {
+		return "PUT"
+	}
+	return link.Method
+}
+
+// SeekReaderAt adapts rs to io.ReaderAt and returns its size.
+// Concurrent ReadAt calls are serialized, since every call
+// seeks the underlying reader.
+func SeekReaderAt(rs io.ReadSeeker) (io.ReaderAt, int64, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	return &seekReaderAt{rs: rs}, size, nil
+}
+
+type seekReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+func (r *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("yadisk: negative offset")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
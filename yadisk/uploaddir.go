@@ -0,0 +1,358 @@
+package yadisk
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultUploadConcurrency is the number of files uploaded
+// in parallel by UploadDir if not set in the options.
+const defaultUploadConcurrency = 4
+
+// SymlinkPolicy tells UploadDir what to do with symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinksSkip skips the links, they are reported as skipped.
+	SymlinksSkip SymlinkPolicy = iota
+
+	// SymlinksFollow uploads the files and folders the links point to.
+	// Links pointing to their own parent folders are skipped.
+	SymlinksFollow
+)
+
+// OverwritePolicy tells UploadDir what to do with existing files.
+type OverwritePolicy int
+
+const (
+	// OverwriteNever skips the files which already exist on Disk.
+	OverwriteNever OverwritePolicy = iota
+
+	// OverwriteChanged overwrites the files whose size or hashes
+	// differ from the local ones, see UploadFileDedup.
+	OverwriteChanged
+
+	// OverwriteAlways overwrites all the existing files.
+	OverwriteAlways
+)
+
+// UploadDirOptions specifies the optional parameters to the
+// ResourcesService.UploadDir method.
+type UploadDirOptions struct {
+	// Glob patterns of the files to upload, in the path.Match syntax.
+	// A pattern with a slash is matched against the slash-separated
+	// path relative to the local folder, otherwise against the file
+	// name. If empty, all the files are uploaded.
+	Include []string
+
+	// Glob patterns of the files and folders to skip, in the same
+	// syntax as Include. The contents of excluded folders are skipped.
+	Exclude []string
+
+	// What to do with symbolic links. Defaults to SymlinksSkip.
+	Symlinks SymlinkPolicy
+
+	// What to do with existing files. Defaults to OverwriteNever.
+	Overwrite OverwritePolicy
+
+	// The number of files uploaded in parallel. Defaults to 4.
+	Concurrency int
+
+	// Bandwidth, if set, limits the total upload rate in addition
+	// to the Client Bandwidth.
+	Bandwidth *BandwidthLimiter
+
+	// Verify controls whether the uploaded files are verified,
+	// see UploadOptions.
+	Verify VerifyMode
+}
+
+// FileUploadResult is the result of uploading a single file by UploadDir.
+type FileUploadResult struct {
+	// Path to the local file and to the file on Disk.
+	LocalPath string
+	Path      Path
+
+	// The size of the local file.
+	Size int64
+
+	// How the file was uploaded. Valid if Err is nil.
+	Status UploadStatus
+
+	// The error which occurred while uploading the file.
+	Err error
+}
+
+// UploadDirReport lists the results of UploadDir in the walk order.
+type UploadDirReport struct {
+	Files []FileUploadResult
+}
+
+// Failed returns the results of the files which failed to upload.
+func (r *UploadDirReport) Failed() []FileUploadResult {
+	var failed []FileUploadResult
+	for _, f := range r.Files {
+		if f.Err != nil {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// uploadDirEntry is a local file found by UploadDir,
+// or a folder which can't be read.
+type uploadDirEntry struct {
+	localPath string
+	rel       string
+	size      int64
+	skipped   bool
+	err       error
+}
+
+// UploadDir uploads the contents of the local folder into the folder
+// at remoteDir, mirroring its structure. The remote folder is created
+// if missing, and its subfolders are created for the files uploaded
+// into them, so the local folders without such files aren't mirrored.
+// The files are uploaded with UploadFile by a pool of workers.
+//
+// The failures of single files don't stop the upload, they are
+// listed in the report. The returned error is set only if the local
+// folder can't be read, a remote folder can't be created, or
+// the context is done.
+func (s *ResourcesService) UploadDir(
+	ctx context.Context,
+	localDir string,
+	remoteDir Path,
+	opt *UploadDirOptions,
+) (*UploadDirReport, error) {
+	if opt == nil {
+		opt = new(UploadDirOptions)
+	}
+	for _, pattern := range append(append([]string(nil), opt.Include...), opt.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &uploadDirWalker{opt: opt, visited: make(map[string]bool)}
+	if err := w.walk(localDir, ""); err != nil {
+		return nil, err
+	}
+
+	if err := s.MkdirAll(ctx, remoteDir); err != nil {
+		return nil, err
+	}
+
+	report := &UploadDirReport{}
+	created := make(map[string]bool)
+	var files []int
+	for _, e := range w.entries {
+		report.Files = append(report.Files, FileUploadResult{
+			LocalPath: e.localPath,
+			Path:      remoteDir.Join(e.rel),
+			Size:      e.size,
+			Status:    UploadSkipped,
+			Err:       e.err,
+		})
+		if e.skipped || e.err != nil {
+			continue
+		}
+		if err := s.mkdirParents(ctx, remoteDir, e.rel, created); err != nil {
+			return nil, err
+		}
+		files = append(files, len(report.Files)-1)
+	}
+
+	concurrency := opt.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultUploadConcurrency
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				f := &report.Files[i]
+				f.Status, f.Err = s.uploadDirFile(ctx, f.LocalPath, f.Path, opt)
+			}
+		}()
+	}
+
+	for n, i := range files {
+		select {
+		case jobs <- i:
+			continue
+		case <-ctx.Done():
+		}
+		for _, i := range files[n:] {
+			report.Files[i].Err = ctx.Err()
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+
+	return report, ctx.Err()
+}
+
+// mkdirParents creates the folders on the relative path of the file
+// in remoteDir, skipping the ones recorded in created.
+func (s *ResourcesService) mkdirParents(
+	ctx context.Context,
+	remoteDir Path,
+	rel string,
+	created map[string]bool,
+) error {
+	var dirs []string
+	for dir := path.Dir(rel); dir != "." && !created[dir]; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if _, _, err := s.Mkdir(ctx, remoteDir.Join(dirs[i])); err != nil && !hasErrorCode(err, errCodeDirExists) {
+			return err
+		}
+		created[dirs[i]] = true
+	}
+	return nil
+}
+
+// uploadDirFile uploads a single file according to the overwrite policy.
+func (s *ResourcesService) uploadDirFile(
+	ctx context.Context,
+	localPath string,
+	path Path,
+	opt *UploadDirOptions,
+) (UploadStatus, error) {
+	uploadOpt := UploadOptions{
+		Overwrite: opt.Overwrite != OverwriteNever,
+		Bandwidth: opt.Bandwidth,
+		Verify:    opt.Verify,
+	}
+
+	switch opt.Overwrite {
+	case OverwriteChanged:
+		result, err := s.UploadFileDedup(ctx, localPath, path, &DedupUploadOptions{
+			UploadOptions: uploadOpt,
+		})
+		if err != nil {
+			return UploadTransferred, err
+		}
+		return result.Status, nil
+	case OverwriteNever:
+		_, err := s.UploadFile(ctx, localPath, path, &uploadOpt)
+		if hasErrorCode(err, errCodeResourceExists) {
+			return UploadSkipped, nil
+		}
+		return UploadTransferred, err
+	default:
+		_, err := s.UploadFile(ctx, localPath, path, &uploadOpt)
+		return UploadTransferred, err
+	}
+}
+
+// uploadDirWalker lists the local files for UploadDir.
+type uploadDirWalker struct {
+	opt     *UploadDirOptions
+	entries []uploadDirEntry
+
+	// Real paths of the folders on the current walk path,
+	// to detect symbolic link loops.
+	visited map[string]bool
+}
+
+// walk lists the contents of the local folder dir, whose path
+// relative to the uploaded folder is rel. Only the error of reading
+// the uploaded folder itself is returned, the other errors are
+// recorded in the entries.
+func (w *uploadDirWalker) walk(dir, rel string) error {
+	realPath, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	w.visited[realPath] = true
+	defer delete(w.visited, realPath)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		localPath := filepath.Join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+		if match(w.opt.Exclude, entryRel) {
+			continue
+		}
+
+		info, err := entry.Info()
+		isLink := err == nil && info.Mode()&os.ModeSymlink != 0
+		if isLink {
+			// The skipped links are filtered by their targets too.
+			target, statErr := os.Stat(localPath)
+			if statErr == nil || w.opt.Symlinks == SymlinksFollow {
+				info, err = target, statErr
+			}
+		}
+		if (err != nil || !info.IsDir()) && len(w.opt.Include) > 0 && !match(w.opt.Include, entryRel) {
+			continue
+		}
+		if err != nil {
+			w.addFile(localPath, entryRel, 0, err)
+			continue
+		}
+
+		switch {
+		case isLink && (w.opt.Symlinks == SymlinksSkip || info.IsDir() && w.isLoop(localPath)):
+			w.entries = append(w.entries, uploadDirEntry{
+				localPath: localPath,
+				rel:       entryRel,
+				skipped:   true,
+			})
+		case info.IsDir():
+			if err := w.walk(localPath, entryRel); err != nil {
+				w.addFile(localPath, entryRel, 0, err)
+			}
+		case info.Mode().IsRegular():
+			w.addFile(localPath, entryRel, info.Size(), nil)
+		}
+	}
+	return nil
+}
+
+// isLoop reports whether the link points to a folder
+// on the current walk path.
+func (w *uploadDirWalker) isLoop(link string) bool {
+	realPath, err := filepath.EvalSymlinks(link)
+	return err == nil && w.visited[realPath]
+}
+
+func (w *uploadDirWalker) addFile(localPath, rel string, size int64, err error) {
+	w.entries = append(w.entries, uploadDirEntry{
+		localPath: localPath,
+		rel:       rel,
+		size:      size,
+		err:       err,
+	})
+}
+
+// match reports whether the slash-separated relative path
+// matches any of the patterns. Patterns without a slash
+// are matched against the base name.
+func match(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package yadisk
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// defaultUsageTop is the default number of the largest
+// files and folders kept in a UsageReport.
+const defaultUsageTop = 10
+
+// UsageOptions specifies the optional parameters to the
+// ResourcesService.Usage method.
+type UsageOptions struct {
+	// The number of the largest files and folders in the report.
+	// Defaults to 10, a negative value disables them.
+	Top int
+
+	// The maximal depth of the folders in the tree: the root is at
+	// depth 0, its subfolders at depth 1 and so on. The contents of the
+	// deeper folders are counted in their ancestor at the maximal
+	// depth. Zero means no limit.
+	MaxDepth int
+
+	// The number of folders listed in parallel, see WalkOptions.
+	Concurrency int
+}
+
+// UsageCount is the total size and the number of files.
+type UsageCount struct {
+	Size  uint `json:"size"`
+	Files int  `json:"files"`
+}
+
+// Usage is the disk usage of a folder, including its subfolders.
+type Usage struct {
+	Path Path   `json:"path"`
+	Name string `json:"name"`
+
+	// The total size and the number of the files in the folder.
+	UsageCount
+
+	// The number of the folders in the folder.
+	Folders int `json:"folders"`
+
+	// The usage by the media type, like "image", and by the MIME type,
+	// like "image/jpeg". The files of unknown type are counted under "".
+	MediaTypes map[string]UsageCount `json:"media_types,omitempty"`
+	MimeTypes  map[string]UsageCount `json:"mime_types,omitempty"`
+
+	// The usage of the subfolders, sorted by name.
+	Children []*Usage `json:"children,omitempty"`
+}
+
+// UsageEntry is a file or folder in the list of the largest ones.
+type UsageEntry struct {
+	Path Path `json:"path"`
+	Size uint `json:"size"`
+}
+
+// UsageReport is the result of ResourcesService.Usage.
+// It's serializable to JSON to keep the history of the usage.
+type UsageReport struct {
+	// The time the analysis started.
+	Time time.Time `json:"time"`
+
+	// The usage tree of the root folder.
+	Root *Usage `json:"root"`
+
+	// The largest files and subfolders of the root, the largest first.
+	LargestFiles   []UsageEntry `json:"largest_files,omitempty"`
+	LargestFolders []UsageEntry `json:"largest_folders,omitempty"`
+}
+
+// Usage walks the tree at the root and aggregates the sizes and the
+// numbers of the files in every folder, see Walk. Unlike Disk.Get,
+// the Trash isn't counted unless the root is in the Trash.
+func (s *ResourcesService) Usage(ctx context.Context, root Path, opt *UsageOptions) (*UsageReport, error) {
+	if opt == nil {
+		opt = new(UsageOptions)
+	}
+	top := opt.Top
+	if top == 0 {
+		top = defaultUsageTop
+	}
+
+	report := &UsageReport{Time: time.Now()}
+	type folder struct {
+		usage *Usage
+		depth int
+	}
+	folders := make(map[Path]folder)
+	var largest []UsageEntry
+
+	err := s.Walk(ctx, root, func(path Path, resource *Resource, err error) error {
+		if err != nil {
+			return err
+		}
+		usage := &Usage{Path: path, Name: resource.Name}
+		if report.Root == nil {
+			// The first call is for the root, which may be a file.
+			report.Root = usage
+			folders[path.Canonical()] = folder{usage: usage}
+		} else if resource.Type == "dir" {
+			parent := folders[path.Dir().Canonical()]
+			if opt.MaxDepth > 0 && parent.depth >= opt.MaxDepth {
+				// Count the contents in the ancestor.
+				parent.usage.Folders++
+				folders[path.Canonical()] = parent
+			} else {
+				parent.usage.Children = append(parent.usage.Children, usage)
+				folders[path.Canonical()] = folder{usage: usage, depth: parent.depth + 1}
+			}
+		}
+		if resource.Type == "dir" {
+			return nil
+		}
+
+		if usage != report.Root {
+			usage = folders[path.Dir().Canonical()].usage
+		}
+		usage.add(resource)
+		if top > 0 {
+			largest = addLargest(largest, UsageEntry{Path: path, Size: resource.Size}, top)
+		}
+		return nil
+	}, &WalkOptions{
+		Concurrency: opt.Concurrency,
+		Fields:      []string{"size", "media_type", "mime_type"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Root.sum()
+	report.LargestFiles = largest
+	if top > 0 {
+		var subfolders []UsageEntry
+		var collect func(u *Usage)
+		collect = func(u *Usage) {
+			for _, child := range u.Children {
+				subfolders = addLargest(subfolders, UsageEntry{Path: child.Path, Size: child.Size}, top)
+				collect(child)
+			}
+		}
+		collect(report.Root)
+		report.LargestFolders = subfolders
+	}
+	return report, nil
+}
+
+// add counts the file in the folder.
+func (u *Usage) add(file *Resource) {
+	count := UsageCount{Size: file.Size, Files: 1}
+	u.UsageCount.add(count)
+	u.MediaTypes = addUsageCount(u.MediaTypes, file.MediaType, count)
+	u.MimeTypes = addUsageCount(u.MimeTypes, file.MimeType, count)
+}
+
+// sum adds the usage of the subfolders to the folder,
+// and sorts them by name.
+func (u *Usage) sum() {
+	for _, child := range u.Children {
+		child.sum()
+		u.UsageCount.add(child.UsageCount)
+		u.Folders += child.Folders + 1
+		for t, count := range child.MediaTypes {
+			u.MediaTypes = addUsageCount(u.MediaTypes, t, count)
+		}
+		for t, count := range child.MimeTypes {
+			u.MimeTypes = addUsageCount(u.MimeTypes, t, count)
+		}
+	}
+	sort.Slice(u.Children, func(i, j int) bool {
+		return u.Children[i].Name < u.Children[j].Name
+	})
+}
+
+func (c *UsageCount) add(count UsageCount) {
+	c.Size += count.Size
+	c.Files += count.Files
+}
+
+// addUsageCount adds the count to the key, allocating the map if needed.
+func addUsageCount(m map[string]UsageCount, key string, count UsageCount) map[string]UsageCount {
+	if m == nil {
+		m = make(map[string]UsageCount)
+	}
+	c := m[key]
+	c.add(count)
+	m[key] = c
+	return m
+}
+
+// addLargest adds the entry to the list sorted by size,
+// keeping at most top largest entries.
+func addLargest(entries []UsageEntry, entry UsageEntry, top int) []UsageEntry {
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Size < entry.Size
+	})
+	if i >= top {
+		return entries
+	}
+	if len(entries) < top {
+		entries = append(entries, UsageEntry{})
+	}
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	return entries
+}
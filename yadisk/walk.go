@@ -0,0 +1,214 @@
+package yadisk
+
+import (
+	"context"
+	"io/fs"
+)
+
+// SkipDir and SkipAll are returned by a WalkFunc to skip the
+// folder or the rest of the walk, like with fs.WalkDir. They are
+// the fs errors, so the same values work for both.
+var (
+	SkipDir = fs.SkipDir
+	SkipAll = fs.SkipAll
+)
+
+// WalkFunc is the function called by Walk for every file and folder,
+// like fs.WalkDirFunc. The path is the root joined with the names of
+// the resources, so it keeps the namespace of the root. It's cleaned,
+// see Path.Join.
+//
+// If the root can't be requested, the function is called with a nil
+// resource and the error. If a folder can't be listed, the function
+// is called for it the second time with the error, and can return
+// SkipDir to continue the walk.
+type WalkFunc func(path Path, resource *Resource, err error) error
+
+// WalkOptions specifies the optional parameters to the
+// ResourcesService.Walk method.
+type WalkOptions struct {
+	// The number of subfolders listed in parallel ahead of the walk.
+	// Defaults to 1, listing every folder when it's visited.
+	Concurrency int
+
+	// The maximal depth of the visited resources: the root is at
+	// depth 0, its contents at depth 1 and so on. The folders at the
+	// maximal depth are visited but not listed. Zero means no limit.
+	MaxDepth int
+
+	// Ordered lists the folders sorted by name, so the order of the
+	// walk is the same every time. Otherwise the order of Disk is used,
+	// which may change when the folder is modified during the walk.
+	Ordered bool
+
+	// Fields are the fields of the resources to request, to keep
+	// the responses small. The name, path and type are always
+	// requested. By default the resources are requested in full.
+	Fields []string
+}
+
+// Walk walks the tree of files and folders at the root, calling fn for
+// each of them, including the root, like fs.WalkDir. The folders are
+// listed page by page, and their contents are visited in pre-order,
+// a folder before its contents. The function is never called
+// concurrently, even when the folders are listed in parallel.
+// The roots in the Trash, like "trash:/", are supported.
+func (s *ResourcesService) Walk(ctx context.Context, root Path, fn WalkFunc, opt *WalkOptions) error {
+	if opt == nil {
+		opt = new(WalkOptions)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &walker{s: s, ctx: ctx, fn: fn, opt: opt}
+	if len(opt.Fields) > 0 {
+		w.fields = append([]string{"name", "path", "type"}, opt.Fields...)
+	}
+	if opt.Ordered {
+		w.sort = "name"
+	}
+	if opt.Concurrency > 1 {
+		w.sem = make(chan struct{}, opt.Concurrency)
+	}
+
+	resource, err := s.getAny(ctx, root, &ResourcesOptions{Fields: w.fields})
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.visit(root, resource, 0, nil)
+	}
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walker keeps the state of a Walk.
+type walker struct {
+	s      *ResourcesService
+	ctx    context.Context
+	fn     WalkFunc
+	opt    *WalkOptions
+	fields []string
+	sort   string
+
+	// sem limits the parallel listings, nil if they are disabled.
+	sem chan struct{}
+}
+
+// listing is the folder listing, possibly in progress.
+type listing struct {
+	done   chan struct{}
+	items  []Resource
+	err    error
+	cancel context.CancelFunc
+}
+
+func (l *listing) wait() ([]Resource, error) {
+	<-l.done
+	return l.items, l.err
+}
+
+// list lists the folder, in the background if parallel listings are enabled.
+func (w *walker) list(dir Path) *listing {
+	ctx, cancel := context.WithCancel(w.ctx)
+	l := &listing{done: make(chan struct{}), cancel: cancel}
+	if w.sem == nil {
+		l.items, l.err = w.s.List(ctx, dir, &ListOptions{Fields: w.fields, Sort: w.sort})
+		close(l.done)
+		return l
+	}
+
+	go func() {
+		defer close(l.done)
+		select {
+		case w.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.err = ctx.Err()
+			return
+		}
+		defer func() { <-w.sem }()
+		l.items, l.err = w.s.List(ctx, dir, &ListOptions{Fields: w.fields, Sort: w.sort})
+	}()
+	return l
+}
+
+// descend reports whether the folders at the depth are listed.
+func (w *walker) descend(depth int) bool {
+	return w.opt.MaxDepth <= 0 || depth < w.opt.MaxDepth
+}
+
+// visit calls the function for the resource and walks its contents
+// if it's a folder. The listing of the folder is l if it's started
+// ahead, or nil.
+func (w *walker) visit(path Path, resource *Resource, depth int, l *listing) error {
+	isDir := resource.Type == "dir"
+	err := w.fn(path, resource, nil)
+	if err != nil || !isDir || !w.descend(depth) {
+		if l != nil {
+			l.cancel()
+		}
+		if err == SkipDir && isDir {
+			err = nil
+		}
+		return err
+	}
+
+	if l == nil {
+		l = w.list(resourcePath(path, resource))
+	}
+	items, err := l.wait()
+	if err != nil {
+		if err = w.fn(path, resource, err); err == SkipDir {
+			err = nil
+		}
+		return err
+	}
+
+	// Keep up to Concurrency subfolder listings in progress ahead.
+	var subdirs []int
+	if w.sem != nil && w.descend(depth+1) {
+		for i := range items {
+			if items[i].Type == "dir" {
+				subdirs = append(subdirs, i)
+			}
+		}
+	}
+	listings := make([]*listing, len(items))
+	defer func() {
+		for _, l := range listings {
+			if l != nil {
+				l.cancel()
+			}
+		}
+	}()
+
+	started, visited := 0, 0
+	for i := range items {
+		for started < len(subdirs) && started < visited+cap(w.sem) {
+			j := subdirs[started]
+			listings[j] = w.list(resourcePath(path.Join(items[j].Name), &items[j]))
+			started++
+		}
+		if listings[i] != nil {
+			visited++
+		}
+		if err := w.visit(path.Join(items[i].Name), &items[i], depth+1, listings[i]); err != nil {
+			if err == SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// resourcePath returns the path of the resource on Disk, or the walked
+// path if it's unknown. They differ in the Trash, where the names of
+// the resources are their original names.
+func resourcePath(path Path, resource *Resource) Path {
+	if resource.Path != "" {
+		return resource.Path
+	}
+	return path
+}
@@ -0,0 +1,224 @@
+package webdavfs
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+	"golang.org/x/net/webdav"
+)
+
+// HandlerOptions specifies the optional parameters to NewHandler.
+type HandlerOptions struct {
+	// Prefix is the URL path prefix stripped from the request paths.
+	Prefix string
+
+	// NewClient returns the client for the OAuth token of a request.
+	// Defaults to yadisk.NewClient. Set it to configure the clients,
+	// for example their retries or bandwidth.
+	NewClient func(token string) *yadisk.Client
+
+	// Logger, if set, is called with every request and its error.
+	Logger func(r *http.Request, err error)
+
+	// LockIdleTimeout is the time the locks of an account are kept
+	// after its last request. Defaults to 1 hour.
+	LockIdleTimeout time.Duration
+}
+
+// defaultLockIdleTimeout is the default HandlerOptions.LockIdleTimeout.
+const defaultLockIdleTimeout = time.Hour
+
+// infiniteTimeout is the lock duration without a timeout,
+// like the one webdav.Handler uses to confirm the locks.
+const infiniteTimeout = -1
+
+// Handler serves the Disk folder of every account over WebDAV. The
+// OAuth token of the account is the basic auth password of the request,
+// the user name is ignored. The requests without credentials are
+// answered with 401 Unauthorized.
+//
+// The locks are kept in memory per account, by the hash of the token.
+// The token is checked with a request for the root folder before its
+// locks are created, and the locks are dropped after LockIdleTimeout
+// without requests. COPY requests with Depth 0 or lock conditions are
+// performed by webdav.Handler, copying the data through the gateway.
+type Handler struct {
+	root string
+	opt  HandlerOptions
+
+	mu    sync.Mutex
+	locks map[[sha256.Size]byte]*accountLocks
+	swept time.Time
+}
+
+// accountLocks is the lock system of an account.
+type accountLocks struct {
+	ls   webdav.LockSystem
+	used time.Time
+}
+
+// NewHandler returns a handler serving the folder at the path root.
+func NewHandler(root string, opt *HandlerOptions) *Handler {
+	if opt == nil {
+		opt = new(HandlerOptions)
+	}
+	h := &Handler{root: root, opt: *opt, locks: make(map[[sha256.Size]byte]*accountLocks)}
+	if h.opt.NewClient == nil {
+		h.opt.NewClient = yadisk.NewClient
+	}
+	if h.opt.LockIdleTimeout <= 0 {
+		h.opt.LockIdleTimeout = defaultLockIdleTimeout
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, token, ok := r.BasicAuth()
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Yandex.Disk"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	client := h.opt.NewClient(token)
+	ls, err := h.lockSystem(r, client, token)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, fs.ErrPermission):
+			w.Header().Set("WWW-Authenticate", `Basic realm="Yandex.Disk"`)
+			status = http.StatusUnauthorized
+		case errors.Is(err, fs.ErrNotExist):
+			status = http.StatusNotFound
+		}
+		http.Error(w, http.StatusText(status), status)
+		if h.opt.Logger != nil {
+			h.opt.Logger(r, err)
+		}
+		return
+	}
+	fsys := New(client, h.root)
+
+	if r.Method == "COPY" && r.Header.Get("Depth") != "0" && r.Header.Get("If") == "" {
+		status, err := h.copy(r, fsys, ls)
+		if status != http.StatusCreated && status != http.StatusNoContent {
+			http.Error(w, http.StatusText(status), status)
+		} else {
+			w.WriteHeader(status)
+		}
+		if h.opt.Logger != nil {
+			h.opt.Logger(r, err)
+		}
+		return
+	}
+
+	dav := &webdav.Handler{
+		Prefix:     h.opt.Prefix,
+		FileSystem: fsys,
+		LockSystem: ls,
+		Logger:     h.opt.Logger,
+	}
+	dav.ServeHTTP(w, r)
+}
+
+// lockSystem returns the lock system of the account. For a new
+// account, the token is checked by requesting the root folder with
+// the client first, so the requests with invalid tokens don't
+// create the lock systems.
+func (h *Handler) lockSystem(r *http.Request, client *yadisk.Client, token string) (webdav.LockSystem, error) {
+	key := sha256.Sum256([]byte(token))
+	if ls := h.usedLocks(key, nil); ls != nil {
+		return ls, nil
+	}
+	_, _, err := client.Resources.Get(r.Context(), yadisk.Path(h.root), &yadisk.ResourcesOptions{
+		Fields: []string{"path"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.usedLocks(key, webdav.NewMemLS()), nil
+}
+
+// usedLocks returns the lock system of the account and marks it used.
+// If the account has none, ls is added for it, and nil ls is returned.
+// The lock systems unused for LockIdleTimeout are dropped.
+func (h *Handler) usedLocks(key [sha256.Size]byte, ls webdav.LockSystem) webdav.LockSystem {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(h.swept) >= h.opt.LockIdleTimeout/4 {
+		for k, locks := range h.locks {
+			if now.Sub(locks.used) >= h.opt.LockIdleTimeout {
+				delete(h.locks, k)
+			}
+		}
+		h.swept = now
+	}
+
+	locks, ok := h.locks[key]
+	if !ok {
+		if ls == nil {
+			return nil
+		}
+		locks = &accountLocks{ls: ls}
+		h.locks[key] = locks
+	}
+	locks.used = now
+	return locks.ls
+}
+
+// copy performs the COPY request with the copy API. Like with
+// webdav.Handler, the destination is locked for the copy, so
+// the copy to a resource locked by a client fails.
+func (h *Handler) copy(r *http.Request, fsys *FileSystem, ls webdav.LockSystem) (int, error) {
+	src, ok := h.stripPrefix(r.URL.Path)
+	if !ok {
+		return http.StatusNotFound, nil
+	}
+	u, err := url.Parse(r.Header.Get("Destination"))
+	if err != nil || u.Path == "" {
+		return http.StatusBadRequest, err
+	}
+	if u.Host != "" && u.Host != r.Host {
+		return http.StatusBadGateway, nil
+	}
+	dst, ok := h.stripPrefix(u.Path)
+	if !ok {
+		return http.StatusBadGateway, nil
+	}
+	if strings.TrimSuffix(src, "/") == strings.TrimSuffix(dst, "/") {
+		return http.StatusForbidden, nil
+	}
+
+	lock, err := ls.Create(time.Now(), webdav.LockDetails{
+		Root:      dst,
+		Duration:  infiniteTimeout,
+		ZeroDepth: true,
+	})
+	if err == webdav.ErrLocked {
+		return http.StatusLocked, err
+	} else if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer ls.Unlock(time.Now(), lock)
+
+	return fsys.Copy(r.Context(), src, dst, r.Header.Get("Overwrite") != "F")
+}
+
+// stripPrefix removes the Prefix from the URL path.
+func (h *Handler) stripPrefix(p string) (string, bool) {
+	if h.opt.Prefix == "" {
+		return p, true
+	}
+	if r := strings.TrimPrefix(p, h.opt.Prefix); len(r) < len(p) {
+		return r, true
+	}
+	return p, false
+}
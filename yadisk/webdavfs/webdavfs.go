@@ -0,0 +1,188 @@
+// Package webdavfs exposes Disk folders over WebDAV. FileSystem
+// implements golang.org/x/net/webdav.FileSystem on top of the REST
+// client, and Handler serves it for multiple accounts, taking the
+// OAuth token of every request from the basic auth password. Usage:
+//
+//	http.Handle("/dav/", webdavfs.NewHandler("/", &webdavfs.HandlerOptions{
+//		Prefix: "/dav",
+//	}))
+//
+// The files are streamed in both directions: GET is served with range
+// requests to Disk and PUT is uploaded while it's received. PROPFIND
+// lists the folders page by page, and MOVE and COPY are performed by
+// Disk with the move and copy API, waiting for the asynchronous
+// operations to finish.
+package webdavfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/chibisov/go-yadisk/yadisk"
+	"github.com/chibisov/go-yadisk/yadisk/diskfs"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem implements webdav.FileSystem for the Disk folder.
+// See diskfs.WriteFS for the supported file semantics.
+type FileSystem struct {
+	client *yadisk.Client
+	root   string
+	wfs    *diskfs.WriteFS
+}
+
+// New returns a file system rooted at the folder at the path,
+// like "/" or "app:/".
+func New(client *yadisk.Client, root string) *FileSystem {
+	return &FileSystem{client: client, root: root, wfs: diskfs.NewWriteFS(client, root)}
+}
+
+// remotePath returns the Disk path of the WebDAV name.
+func (fsys *FileSystem) remotePath(name string) yadisk.Path {
+	return yadisk.Path(fsys.root).Join(path.Clean("/" + name))
+}
+
+// Mkdir creates the folder.
+func (fsys *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return fsError(fsys.wfs.WithContext(ctx).Mkdir(name, perm))
+}
+
+// OpenFile opens the file or folder.
+func (fsys *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := fsys.wfs.WithContext(ctx).OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, fsError(err)
+	}
+	return &file{File: f}, nil
+}
+
+// RemoveAll removes the file or folder with its contents.
+// The root folder can't be removed.
+func (fsys *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	if path.Clean("/"+name) == "/" {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	return fsError(fsys.wfs.WithContext(ctx).RemoveAll(name))
+}
+
+// Rename moves the file or folder with the move API.
+func (fsys *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return fsError(fsys.wfs.WithContext(ctx).Rename(oldName, newName))
+}
+
+// Stat returns the file info of the file or folder.
+func (fsys *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := fsys.wfs.WithContext(ctx).Stat(name)
+	if err != nil {
+		return nil, fsError(err)
+	}
+	return fileInfo{info}, nil
+}
+
+// Copy copies the file or folder with the copy API, replacing
+// the existing destination if overwrite is set. It returns
+// the HTTP status of the WebDAV COPY response.
+func (fsys *FileSystem) Copy(ctx context.Context, src, dst string, overwrite bool) (int, error) {
+	if _, err := fsys.Stat(ctx, src); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return http.StatusNotFound, err
+		}
+		return http.StatusInternalServerError, err
+	}
+	_, err := fsys.Stat(ctx, dst)
+	exists := err == nil
+	switch {
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return http.StatusInternalServerError, err
+	case exists && !overwrite:
+		return http.StatusPreconditionFailed, fs.ErrExist
+	}
+
+	resources := fsys.client.Resources
+	_, resp, err := resources.Copy(ctx, fsys.remotePath(src), fsys.remotePath(dst), &yadisk.CopyOptions{
+		Overwrite: overwrite,
+	})
+	if err == nil {
+		err = resources.WaitOperation(ctx, resp)
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusConflict, err
+	case err != nil:
+		return http.StatusInternalServerError, err
+	case exists:
+		return http.StatusNoContent, nil
+	}
+	return http.StatusCreated, nil
+}
+
+// fsError replaces the error of the path error with the matching
+// fs error, since webdav.Handler checks the errors with os.IsNotExist
+// and the like, which don't unwrap the API errors.
+func fsError(err error) error {
+	for _, target := range []error{fs.ErrNotExist, fs.ErrExist, fs.ErrPermission} {
+		if !errors.Is(err, target) {
+			continue
+		}
+		switch e := err.(type) {
+		case *fs.PathError:
+			return &fs.PathError{Op: e.Op, Path: e.Path, Err: target}
+		case *os.LinkError:
+			return &os.LinkError{Op: e.Op, Old: e.Old, New: e.New, Err: target}
+		}
+	}
+	return err
+}
+
+// file wraps the diskfs file to describe the resources
+// with the metainformation available from Disk.
+type file struct {
+	diskfs.File
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{info}, nil
+}
+
+func (f *file) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := f.File.Readdir(count)
+	for i := range infos {
+		infos[i] = fileInfo{infos[i]}
+	}
+	return infos, err
+}
+
+// fileInfo implements webdav.ContentTyper and webdav.ETager
+// with the MIME type and the MD5 hash reported by Disk, so the
+// files aren't downloaded to find them.
+type fileInfo struct {
+	os.FileInfo
+}
+
+// resource returns the metainformation of the resource.
+func (fi fileInfo) resource() *yadisk.Resource {
+	resource, _ := fi.Sys().(*yadisk.Resource)
+	return resource
+}
+
+func (fi fileInfo) ContentType(ctx context.Context) (string, error) {
+	if r := fi.resource(); r != nil && r.MimeType != "" {
+		return r.MimeType, nil
+	}
+	return "", webdav.ErrNotImplemented
+}
+
+func (fi fileInfo) ETag(ctx context.Context) (string, error) {
+	if r := fi.resource(); r != nil && r.MD5 != "" {
+		return `"` + r.MD5 + `"`, nil
+	}
+	return "", webdav.ErrNotImplemented
+}
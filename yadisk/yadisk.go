@@ -8,13 +8,18 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-
-	"golang.org/x/net/context/ctxhttp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
 	defaultBaseURL = "https://cloud-api.yandex.net/"
 	apiVersion     = "1"
+
+	// Limits for the delay between retries of a failed request.
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
 )
 
 // A Client manages communication with the Yandex.Disk API.
@@ -26,9 +31,33 @@ type Client struct {
 	// Base URL for API requests. Defaults to the public Yandex.Disk API.
 	BaseURL *url.URL
 
+	// MaxRetries is the number of times a request is sent again
+	// if the API responds with 429 Too Many Requests or a 5xx status,
+	// or if a network error occurs. Requests whose body can't be
+	// replayed are never retried, and POST requests, which may not be
+	// idempotent, are retried on 429 Too Many Requests only. A response
+	// asking to retry after more than 30 seconds is returned as is.
+	// Defaults to zero, no retries.
+	MaxRetries int
+
+	// Bandwidth, if set, limits the transfer rate of all uploads
+	// and downloads made by the client.
+	Bandwidth *BandwidthLimiter
+
+	// Observer, if set, is notified about requests, retries
+	// and transferred bytes. See the metrics package for
+	// a Prometheus collector implementing it.
+	Observer Observer
+
 	// Services used for talking to different parts of the Yandex.Disk API.
 	Disk      *DiskService
 	Resources *ResourcesService
+
+	// scope is set for the clients scoped to the application folder.
+	scope *appScope
+
+	// policy is set for the clients created by WithPolicy.
+	policy *Policy
 }
 
 type service struct {
@@ -53,55 +82,223 @@ func NewClient(accessToken string) *Client {
 	return c
 }
 
-// NewRequest creates an API request. A relative URL can be provided
-// in urlStr, which will be resolved to the BaseURL of the Client.
-// Relative URLS should always be specified without a preceding slash.
-// If specified, the value pointed to by body is JSON encoded
-// and included in as the request body.
-func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Request, error) {
-	rel, err := url.Parse(urlStr)
+// RequestOption customizes the request created by NewRequest.
+type RequestOption func(req *http.Request)
+
+// WithQuery adds the values to the query string of the request URL.
+func WithQuery(values url.Values) RequestOption {
+	return func(req *http.Request) {
+		q := req.URL.Query()
+		for key, vs := range values {
+			for _, v := range vs {
+				q.Add(key, v)
+			}
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+}
+
+// WithContentType sets the Content-Type header of the request.
+func WithContentType(contentType string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set("Content-Type", contentType)
+	}
+}
+
+// NewRequest creates an API request with the background context.
+// See NewRequestWithContext for details.
+func (c *Client) NewRequest(method, urlStr string, body interface{}, opts ...RequestOption) (*http.Request, error) {
+	return c.NewRequestWithContext(context.Background(), method, urlStr, body, opts...)
+}
+
+// NewRequestWithContext creates an API request bound to ctx.
+//
+// A relative URL can be provided in urlStr, which will be resolved
+// to the API version path of the BaseURL of the Client, for example
+// "disk/resources" becomes "https://cloud-api.yandex.net/v1/disk/resources".
+// The path is used as is, so the trailing slash must be included
+// only for the endpoints which have it, like "disk/".
+// An absolute URL, like the href of a Link, is used without changes.
+// The OAuth token is sent only to the BaseURL host.
+//
+// If body is an io.Reader, it is sent as is with
+// the "application/octet-stream" content type, unless another one
+// is set by the WithContentType option. Otherwise, if specified,
+// the value pointed to by body is JSON encoded and included
+// in as the request body.
+func (c *Client) NewRequestWithContext(
+	ctx context.Context,
+	method string,
+	urlStr string,
+	body interface{},
+	opts ...RequestOption,
+) (*http.Request, error) {
+	u, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
 	}
 
 	// Build the request url.
-	rel.Path = "v" + apiVersion + "/" + rel.Path + "/"
-	u := c.BaseURL.ResolveReference(rel)
+	if !u.IsAbs() {
+		u.Path = "v" + apiVersion + "/" + strings.TrimPrefix(u.Path, "/")
+		u = c.BaseURL.ResolveReference(u)
+	}
 
 	// Build the request body.
-	var buf io.ReadWriter
-	if body != nil {
-		buf = new(bytes.Buffer)
-		err = json.NewEncoder(buf).Encode(body)
+	var (
+		buf         io.Reader
+		contentType string
+	)
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		buf = b
+		contentType = "application/octet-stream"
+	default:
+		jsonBuf := new(bytes.Buffer)
+		err = json.NewEncoder(jsonBuf).Encode(body)
 		if err != nil {
 			return nil, err
 		}
+		buf = jsonBuf
+		contentType = "application/json"
 	}
 
 	// Build the http request.
-	req, err := http.NewRequest(method, u.String(), buf)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set the necessary headers.
-	req.Header.Set("Content-Type", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", "OAuth "+c.AccessToken)
+	if u.Host == c.BaseURL.Host {
+		req.Header.Set("Authorization", "OAuth "+c.AccessToken)
+	}
+
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	if c.scope != nil {
+		if err := c.scope.rewrite(c, req); err != nil {
+			return nil, err
+		}
+	}
+	if c.policy != nil {
+		if err := c.policy.check(c, req); err != nil {
+			return nil, err
+		}
+	}
 
 	return req, nil
 }
 
-// Do sends an API request and returns the API response.
+// endpoint returns the API endpoint of the request, like "disk/resources",
+// or false if the request isn't sent to the API.
+func (c *Client) endpoint(req *http.Request) (string, bool) {
+	base := c.BaseURL.ResolveReference(&url.URL{Path: "v" + apiVersion + "/"})
+	if req.URL.Host != base.Host || !strings.HasPrefix(req.URL.Path, base.Path) {
+		return "", false
+	}
+	return strings.TrimPrefix(req.URL.Path, base.Path), true
+}
+
+// Do sends an API request and returns the API response
+// wrapped into the Response with the parsed metadata.
 // The API response is JSON decoded and stored in the value
 // pointed to by v, or returned as an error if an API error has occurred.
 // If v implements the io.Writer interface,
 // the raw response will be written to v, without attempting to decode it.
-// The provided ctx must be non-nil. If it is canceled or times out,
+// If the request context is canceled or times out,
 // ctx.Err() will be returned.
-func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
+// The request is retried up to MaxRetries times on throttling,
+// server and network errors.
+func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
+	ctx := req.Context()
+	op := operationFromContext(ctx)
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.do(op, req, v)
+		if response != nil {
+			response.Retries = attempt
+			response.Latency = time.Since(start)
+		}
+		if attempt >= c.MaxRetries || !shouldRetry(ctx, req, response, err) {
+			return response, err
+		}
+		delay := retryDelay(attempt, response)
+		if delay > maxRetryDelay {
+			// Don't keep the caller waiting for long.
+			return response, err
+		}
+
+		// Rewind the request body before sending it again.
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return response, err
+			}
+			req.Body = body
+		}
+
+		c.observe(Event{Kind: EventRetry, Operation: op})
+		select {
+		case <-ctx.Done():
+			return response, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send sends the request with the HTTP client for Client.do and
+// Client.stream, and reports the throttled responses.
+func (c *Client) send(op string, req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		// Prefer the context error if the context has been canceled.
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.observe(Event{
+			Kind:       EventThrottled,
+			Operation:  op,
+			StatusCode: resp.StatusCode,
+		})
+	}
+	return resp, nil
+}
+
+// do makes a single attempt of sending the request for Do.
+func (c *Client) do(op string, req *http.Request, v interface{}) (response *Response, err error) {
+	done := c.observeRequest(op)
+	defer func() { done(response, err) }()
+
+	// Count the sent bytes, the length of the streamed bodies is unknown.
+	if req.Body != nil && req.Body != http.NoBody {
+		sent := &countingBody{ReadCloser: req.Body}
+		req = req.WithContext(req.Context())
+		req.Body = sent
+		defer func() {
+			if n := sent.count(); n > 0 {
+				e := Event{Kind: EventBytesUploaded, Operation: op, Bytes: n}
+				if response != nil {
+					e.StatusCode = response.StatusCode
+				}
+				c.observe(e)
+			}
+		}()
+	}
+
 	// Make the http request.
-	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req) // todo: test context
+	resp, err := c.send(op, req)
 	if err != nil {
 		return nil, err
 	}
@@ -115,36 +312,102 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*htt
 		resp.Body.Close()
 	}()
 
+	response = newResponse(resp)
+
 	// Check for the response errors.
 	if err = checkResponse(resp); err != nil {
-		return resp, err
+		return response, err
+	}
+
+	// The body of the 202 Accepted response is the link
+	// to the asynchronous operation status.
+	body := io.Reader(resp.Body)
+	if resp.StatusCode == http.StatusAccepted {
+		var data []byte
+		data, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return response, err
+		}
+		link := new(Link)
+		if json.Unmarshal(data, link) == nil && link.Href != "" {
+			response.Operation = link
+		}
+		body = bytes.NewReader(data)
 	}
 
 	// Fill the v variable with the response data if it's provided.
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
 			// Write to the buffer if io.Writer is provided.
-			_, err = io.Copy(w, resp.Body)
+			var n int64
+			n, err = io.Copy(w, body)
+			c.observe(Event{
+				Kind:       EventBytesDownloaded,
+				Operation:  op,
+				StatusCode: resp.StatusCode,
+				Bytes:      n,
+			})
 			if err != nil {
 				return nil, err
 			}
 		} else {
 			// Decode JSON to the struct if struct is provided.
-			err = json.NewDecoder(resp.Body).Decode(v)
+			err = json.NewDecoder(body).Decode(v)
 			if err == io.EOF {
 				err = nil // ignore EOF errors caused by empty response body
 			}
+			if err == nil && c.scope != nil {
+				err = c.scope.unscope(req.Context(), c, v)
+			}
 		}
 	}
 
-	return resp, err
+	return response, err
+}
+
+// shouldRetry reports whether the request that finished
+// with resp and err can be sent again.
+func shouldRetry(ctx context.Context, req *http.Request, resp *Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if req.Method == "POST" {
+		// Only the throttled requests surely haven't been performed.
+		return resp != nil && resp.StatusCode == http.StatusTooManyRequests
+	}
+	if resp == nil {
+		return err != nil
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryDelay returns the time to wait before the next attempt.
+// The Retry-After header of the response is respected, otherwise
+// the delay grows exponentially with every attempt up to maxRetryDelay.
+func retryDelay(attempt int, resp *Response) time.Duration {
+	if resp != nil {
+		if s := resp.Header.Get("Retry-After"); s != "" {
+			if seconds, err := strconv.Atoi(s); err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	delay := minRetryDelay << uint(attempt)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
 }
 
 // checkResponse checks the API response for errors,
 // and returns them if present.
 func checkResponse(r *http.Response) error {
 	if r.StatusCode >= 400 {
-		apiErr := new(APIError)
+		apiErr := &APIError{StatusCode: r.StatusCode}
 
 		// Skipping the json decoding errors.
 		json.NewDecoder(r.Body).Decode(apiErr)